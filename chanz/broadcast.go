@@ -0,0 +1,268 @@
+package chanz
+
+import "sync"
+
+// OverflowPolicy decides what happens when a bounded output is full and a new item is to be written, see OpOverflow
+type OverflowPolicy int
+
+const (
+	OverflowBlock OverflowPolicy = iota
+	OverflowDropNewest
+	OverflowDropOldest
+	OverflowDisconnect
+	OverflowError
+)
+
+// OpOverflow sets what happens when a subscriber, or other bounded output, is full and a new item is to be written.
+// OverflowBlock waits until there is room, OverflowDropNewest drops the new item, OverflowDropOldest drops the oldest
+// item in the buffer to make room for the new one and OverflowDisconnect closes the output. OverflowError closes the
+// output as well, and reports ErrOverflow to the stage, see Elastic. Default is OverflowBlock
+func OpOverflow(policy OverflowPolicy) Option {
+	return func(s settings) settings {
+		s.overflow = policy
+		return s
+	}
+}
+
+type subscriber[A any] struct {
+	mu       sync.Mutex
+	c        chan A
+	closed   bool
+	overflow OverflowPolicy
+	quit     chan struct{}
+	once     sync.Once
+}
+
+// close releases a Publish blocked on the subscriber and then closes its chan
+func (s *subscriber[A]) close() {
+	s.once.Do(func() {
+		close(s.quit)
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+}
+
+// Broadcaster writes every item published to it onto the chan of every current subscriber. Subscribers can be added
+// and removed at any time, and each one decides, through OpBuffer and OpOverflow, how to deal with being slow.
+type Broadcaster[A any] struct {
+	pub    sync.Mutex
+	mu     sync.Mutex
+	subs   map[<-chan A]*subscriber[A]
+	closed chan struct{}
+	once   sync.Once
+	stop   *stopper // Stops the stage started by Broadcast, once Close is called
+}
+
+// NewBroadcaster returns a Broadcaster without any subscribers.
+// It will close once Close is called, "done" channel is closed or the context.Done is closed, which is supplied in Option
+func NewBroadcaster[A any](options ...Option) *Broadcaster[A] {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	return newBroadcaster[A](s)
+}
+
+// newBroadcaster returns a Broadcaster that closes once "done", of s, is closed
+func newBroadcaster[A any](s settings) *Broadcaster[A] {
+	b := &Broadcaster[A]{
+		subs:   map[<-chan A]*subscriber[A]{},
+		closed: make(chan struct{}),
+	}
+	if s.done != nil {
+		go func() {
+			select {
+			case <-s.done:
+				b.close()
+			case <-b.closed:
+			}
+		}()
+	}
+	return b
+}
+
+// Broadcast returns a Broadcaster that publishes every item read from "in", and closes once "in" is closed.
+// It will close once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Broadcast[A any](in <-chan A, options ...Option) *Broadcaster[A] {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	// The Broadcaster shares "done" with the stage, and Close stops the stage, so that in is drained either way
+	if s.stop == nil {
+		s.stop = &stopper{quit: make(chan struct{})}
+	}
+	b := newBroadcaster[A](s)
+	b.stop = s.stop
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer b.close()
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			if !b.Publish(e) {
+				return
+			}
+		}
+	}()
+	return b
+}
+
+// Subscribe returns a chan on which every item published from now on is written.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0, and OpOverflow decides what
+// happens when it is full.
+// It will be unsubscribed once Unsubscribe is called, "done" channel is closed or the context.Done is closed, which is
+// supplied in Option. If the Broadcaster is closed, the return chan is closed.
+func (b *Broadcaster[A]) Subscribe(options ...Option) <-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	sub := &subscriber[A]{
+		c:        make(chan A, s.buffer),
+		overflow: s.overflow,
+		quit:     make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-b.closed:
+		close(sub.c)
+		return sub.c
+	default:
+	}
+	b.subs[sub.c] = sub
+
+	if s.done != nil {
+		go func() {
+			select {
+			case <-s.done:
+				b.Unsubscribe(sub.c)
+			case <-sub.quit:
+			}
+		}()
+	}
+	return sub.c
+}
+
+// Unsubscribe removes a subscriber and closes its chan. Items buffered in the chan can still be read.
+func (b *Broadcaster[A]) Unsubscribe(c <-chan A) {
+	b.mu.Lock()
+	sub, ok := b.subs[c]
+	delete(b.subs, c)
+	b.mu.Unlock()
+	if ok {
+		sub.close()
+	}
+}
+
+// Publish writes a onto the chan of every subscriber, following the overflow policy of each one.
+// It returns false if the Broadcaster is closed.
+func (b *Broadcaster[A]) Publish(a A) bool {
+	b.pub.Lock() // Makes sure that every subscriber sees items in the same order
+	defer b.pub.Unlock()
+
+	b.mu.Lock()
+	select {
+	case <-b.closed:
+		b.mu.Unlock()
+		return false
+	default:
+	}
+	subs := make([]*subscriber[A], 0, len(b.subs))
+	for _, sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		if !b.deliver(sub, a) {
+			b.Unsubscribe(sub.c)
+		}
+	}
+	return true
+}
+
+// deliver writes a to the subscriber, it returns false if the subscriber is to be disconnected
+func (b *Broadcaster[A]) deliver(sub *subscriber[A], a A) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return true
+	}
+
+	switch sub.overflow {
+	case OverflowDropNewest:
+		select {
+		case sub.c <- a:
+		default:
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case sub.c <- a:
+				return true
+			default:
+			}
+			select {
+			case <-sub.c:
+			default:
+				if cap(sub.c) == 0 { // Nothing to drop, the new item is dropped instead
+					return true
+				}
+			}
+		}
+	case OverflowDisconnect, OverflowError:
+		select {
+		case sub.c <- a:
+		default:
+			return false
+		}
+	default:
+		select {
+		case <-b.closed:
+		case <-sub.quit:
+		case sub.c <- a:
+		}
+	}
+	return true
+}
+
+// Subscribers returns the number of current subscribers
+func (b *Broadcaster[A]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close closes the chan of every subscriber, after which Publish returns false and Subscribe returns closed chans.
+// A Broadcaster returned by Broadcast stops reading "in", and drains it, as if OpDrain was supplied.
+// It is safe to call Close more than once.
+func (b *Broadcaster[A]) Close() {
+	b.stop.stop()
+	b.close()
+}
+
+// close closes the chan of every subscriber, without stopping the stage started by Broadcast
+func (b *Broadcaster[A]) close() {
+	b.once.Do(func() {
+		close(b.closed)
+		b.mu.Lock()
+		subs := b.subs
+		b.subs = map[<-chan A]*subscriber[A]{}
+		b.mu.Unlock()
+		for _, sub := range subs {
+			sub.close()
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package chanz
+
+import (
+	"context"
+	"github.com/modfin/henry/slicez"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcast(t *testing.T) {
+	b := NewBroadcaster[int]()
+	subs := []<-chan int{b.Subscribe(), b.Subscribe(), b.Subscribe()}
+
+	var wg sync.WaitGroup
+	res := make([][]int, len(subs))
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub <-chan int) {
+			defer wg.Done()
+			res[i] = Collect(sub)
+		}(i, sub)
+	}
+
+	for i := 1; i <= 5; i++ {
+		b.Publish(i)
+	}
+	b.Close()
+	wg.Wait()
+
+	exp := []int{1, 2, 3, 4, 5}
+	for i, r := range res {
+		if !slicez.Equal(r, exp) {
+			t.Logf("expected, %v, but got %v, for subscriber %d", exp, r, i)
+			t.Fail()
+		}
+	}
+
+	if b.Publish(6) {
+		t.Log("expected publish to a closed broadcaster to return false")
+		t.Fail()
+	}
+}
+
+func TestBroadcastFromChan(t *testing.T) {
+	in := make(chan int)
+	b := Broadcast(in)
+	sub := b.Subscribe(OpBuffer(10))
+
+	go func() {
+		defer close(in)
+		for _, i := range []int{1, 2, 3} {
+			in <- i
+		}
+	}()
+
+	res := Collect(sub)
+	exp := []int{1, 2, 3}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestBroadcastOverflow(t *testing.T) {
+	b := NewBroadcaster[int]()
+	newest := b.Subscribe(OpBuffer(2), OpOverflow(OverflowDropNewest))
+	oldest := b.Subscribe(OpBuffer(2), OpOverflow(OverflowDropOldest))
+	disconnect := b.Subscribe(OpBuffer(2), OpOverflow(OverflowDisconnect))
+
+	for i := 1; i <= 4; i++ {
+		b.Publish(i)
+	}
+
+	if b.Subscribers() != 2 {
+		t.Logf("expected 2 subscribers, but got %d", b.Subscribers())
+		t.Fail()
+	}
+	b.Close()
+
+	tests := []struct {
+		name string
+		c    <-chan int
+		exp  []int
+	}{
+		{name: "drop newest", c: newest, exp: []int{1, 2}},
+		{name: "drop oldest", c: oldest, exp: []int{3, 4}},
+		{name: "disconnect", c: disconnect, exp: []int{1, 2}},
+	}
+	for _, test := range tests {
+		res := Collect(test.c)
+		if !slicez.Equal(res, test.exp) {
+			t.Logf("expected, %v, but got %v, for %s", test.exp, res, test.name)
+			t.Fail()
+		}
+	}
+}
+
+func TestBroadcastUnsubscribeBlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := NewBroadcaster[int]()
+	slow := b.Subscribe(OpContext(ctx))
+
+	published := make(chan struct{})
+	go func() {
+		b.Publish(1)
+		close(published)
+	}()
+
+	cancel()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Log("expected publish to be released once the blocking subscriber unsubscribed")
+		t.Fail()
+	}
+
+	DropAll(slow, false)
+	if b.Subscribers() != 0 {
+		t.Logf("expected 0 subscribers, but got %d", b.Subscribers())
+		t.Fail()
+	}
+}
+
+func TestBroadcastCloseDrains(t *testing.T) {
+	in, finished := unbuffered(10)
+	done := make(chan struct{})
+	defer close(done)
+	b := Broadcast(in, OpDone(done))
+	b.Close()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Log("expected in to be drained once the broadcaster is closed")
+		t.Fail()
+	}
+}
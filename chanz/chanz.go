@@ -1,20 +1,56 @@
+// Package chanz contains generic functions and stages for working with channels.
+//
+// Every stage follows the same shutdown contract. It closes its output chans once its input chans are closed, or once
+// the "done" chan or the context, supplied by OpDone and OpContext, is closed. A stage that stops because of "done" no
+// longer reads its input chans, which may leave a producer upstream blocked writing to it if that producer does not
+// watch the same "done". If OpDrain is supplied, the stage instead keeps reading and discarding items from its input
+// chans until they are closed, so that nothing upstream is left blocked.
 package chanz
 
 import (
 	"context"
 	"github.com/modfin/henry/slicez"
 	"sync"
+	"time"
 )
 
 type settings struct {
-	done   <-chan struct{}
-	buffer int
+	ctx           context.Context
+	done          <-chan struct{}
+	buffer        int
+	workers       int
+	unordered     bool
+	failFast      bool
+	cancel        func()
+	clock         Clock
+	overflow      OverflowPolicy
+	starvation    int
+	recover       func(p Panic) bool
+	drain         bool
+	observer      Observer
+	name          string
+	fail          func(err error)
+	reserve       int
+	softCap       int
+	softCapped    func(length int)
+	hardCap       int
+	elasticStats  *ElasticStats
+	groupIdle     time.Duration
+	maxGroups     int
+	distinctStats func(stats DistinctStats)
+	join          JoinMode
+	joinExpiredL  any
+	joinExpiredR  any
+	jitter        time.Duration
+	startDelay    *time.Duration
+	stop          *stopper
 }
 
 type Option func(s settings) settings
 
 func OpContext(ctx context.Context) Option {
 	return func(s settings) settings {
+		s.ctx = ctx
 		s.done = SomeDone(ctx.Done(), s.done)
 		return s
 	}
@@ -32,23 +68,71 @@ func OpDone(done <-chan struct{}) Option {
 	}
 }
 
+// OpWorkers sets the number of goroutines that a stage, such as Map, Filter and Peek, will use to apply its func.
+// Results are emitted in input order unless OpUnordered is supplied as well. Default is 1.
+func OpWorkers(n int) Option {
+	return func(s settings) settings {
+		s.workers = n
+		return s
+	}
+}
+
+// OpUnordered lets a stage running with OpWorkers emit results as soon as they are done, instead of in input order
+func OpUnordered() Option {
+	return func(s settings) settings {
+		s.unordered = true
+		return s
+	}
+}
+
+// OpFailFast makes error aware stages, such as MapErr, stop at the first error. The error is emitted, cancel is called
+// so that every stage sharing its context or done chan stops as well, and then the output chan is closed.
+// cancel may be nil. Without OpFailFast, error aware stages keep going and every error is emitted.
+func OpFailFast(cancel func()) Option {
+	return func(s settings) settings {
+		s.failFast = true
+		s.cancel = cancel
+		return s
+	}
+}
+
 // Map will take a chan, in, and executes mapper and put the resulting on to the return chan.
 // The return chan has a buffer of buffer size supplied in input Option, default is 0.
 // It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpWorkers is supplied, up to n mappers will run concurrently
 func Map[A any, B any](in <-chan A, mapper func(a A) B, options ...Option) <-chan B {
 	var s settings
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
+
+	if s.workers > 1 {
+		return mapConcurrent(in, func(a A) (B, bool) {
+			return mapper(a), true
+		}, s)
+	}
 
 	out := make(chan B, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer observeClosed(s)
 		defer close(out)
-		for e := range in {
-			select {
-			case <-s.done:
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			var b B
+			switch call(s, func() { b = mapper(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, out, b) {
 				return
-			case out <- mapper(e):
 			}
 		}
 	}()
@@ -67,6 +151,7 @@ func MapWith[A any, B any](options ...Option) func(in <-chan A, mapper func(a A)
 // Peek will take a chan, in, and executes apply on every element and then writes the element to the return chan.
 // The return chan has a buffer of buffer size supplied in input Option, default is 0.
 // It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpWorkers is supplied, up to n apply funcs will run concurrently
 func Peek[A any](in <-chan A, apply func(a A), options ...Option) <-chan A {
 	return Map(in, func(a A) A {
 		apply(a)
@@ -95,17 +180,23 @@ func Flatten[A any](in <-chan []A, options ...Option) <-chan A {
 
 	out := make(chan A, s.buffer)
 	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
 		defer close(out)
-		for slice := range in {
+		for {
+			slice, more := receive(s, in)
+			if !more {
+				return
+			}
 			if len(slice) == 0 {
 				continue
 			}
-			select {
-			case <-s.done:
+			if !send(s, out, slice[0]) {
 				return
-			case out <- slice[0]:
-				for _, e := range slice[1:] {
-					out <- e
+			}
+			for _, e := range slice[1:] {
+				if !send(s, out, e) {
+					return
 				}
 			}
 		}
@@ -127,13 +218,13 @@ func Generator[A any](gen func(func(A)), options ...Option) <-chan A {
 	out := make(chan A, s.buffer)
 
 	yield := func(a A) {
-		select {
-		case <-s.done:
+		if !send(s, out, a) {
 			return
-		case out <- a:
 		}
 	}
 	go func() {
+		defer recoverStage(s)
+		defer observeClosed(s)
 		defer close(out)
 		gen(yield)
 	}()
@@ -160,12 +251,11 @@ func GenerateWith[A any](options ...Option) func(elements ...A) <-chan A {
 		}
 		out := make(chan A, s.buffer)
 		go func() {
+			defer observeClosed(s)
 			defer close(out)
 			for _, e := range elements {
-				select {
-				case <-s.done:
+				if !send(s, out, e) {
 					return
-				case out <- e:
 				}
 			}
 		}()
@@ -193,12 +283,15 @@ func FanInWith[A any](options ...Option) func(cs ...<-chan A) <-chan A {
 		var wg sync.WaitGroup
 		out := make(chan A, s.buffer)
 		output := func(c <-chan A) {
+			defer drain(s, c)
 			defer wg.Done()
-			for e := range c {
-				select {
-				case <-s.done:
+			for {
+				e, more := receive(s, c)
+				if !more {
+					return
+				}
+				if !send(s, out, e) {
 					return
-				case out <- e:
 				}
 			}
 		}
@@ -210,6 +303,7 @@ func FanInWith[A any](options ...Option) func(cs ...<-chan A) <-chan A {
 		go func() {
 			wg.Wait()
 			close(out)
+			observeClosed(s)
 		}()
 		return out
 	}
@@ -232,19 +326,22 @@ func FanOut[A any](c <-chan A, size int, options ...Option) []<-chan A {
 	}
 
 	go func() {
+		defer drain(s, c)
 		defer func() {
 			for _, o := range outs {
 				close(o)
 			}
+			observeClosed(s)
 		}()
 
-		for e := range c {
-			select {
-			case <-s.done:
+		for {
+			e, more := receive(s, c)
+			if !more {
 				return
-			case outs[0] <- e: // Might want to do this concurrently somehow?
-				for _, o := range outs[1:] {
-					o <- e
+			}
+			for _, o := range outs { // Might want to do this concurrently somehow?
+				if !send(s, o, e) {
+					return
 				}
 			}
 		}
@@ -280,13 +377,17 @@ func ConcatWith[A any](options ...Option) func(cs ...<-chan A) <-chan A {
 
 		out := make(chan A, s.buffer)
 		go func() {
+			defer drain(s, cs...)
+			defer observeClosed(s)
 			defer close(out)
 			for _, c := range cs {
-				for e := range c {
-					select {
-					case <-s.done:
+				for {
+					e, more := receive(s, c)
+					if !more {
+						break
+					}
+					if !send(s, out, e) {
 						return
-					case out <- e:
 					}
 				}
 			}
@@ -298,23 +399,40 @@ func ConcatWith[A any](options ...Option) func(cs ...<-chan A) <-chan A {
 // Filter takes a chan and applies the "include" func to every item. If it returns true, the item is out on the output chan
 // The return chan has a buffer of buffer size supplied in input Option, default is 0.
 // It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpWorkers is supplied, up to n include funcs will run concurrently
 func Filter[A any](c <-chan A, include func(a A) bool, options ...Option) <-chan A {
 	var s settings
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
+
+	if s.workers > 1 {
+		return mapConcurrent(c, func(a A) (A, bool) {
+			return a, include(a)
+		}, s)
+	}
 
 	out := make(chan A, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(out)
-		for e := range c {
-			if !include(e) {
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
+			var keep bool
+			if call(s, func() { keep = include(e) }) == callStop {
+				return
+			}
+			if !keep {
 				continue
 			}
-			select {
-			case <-s.done:
+			if !send(s, out, e) {
 				return
-			case out <- e:
 			}
 		}
 	}()
@@ -339,29 +457,40 @@ func Compact[A any](c <-chan A, equal func(a, b A) bool, options ...Option) <-ch
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
 	out := make(chan A, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(out)
 
-		var a, ok = <-c
+		var a, ok = receive(s, c)
 		if !ok {
 			return
 		}
-		select {
-		case <-s.done:
+		if !send(s, out, a) {
 			return
-		case out <- a:
 		}
 
-		for b := range c {
-			if equal(a, b) {
+		for {
+			b, more := receive(s, c)
+			if !more {
+				return
+			}
+			var eq bool
+			switch call(s, func() { eq = equal(a, b) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if eq {
 				continue
 			}
 			a = b
-			select {
-			case <-s.done:
+			if !send(s, out, b) {
 				return
-			case out <- b:
 			}
 		}
 	}()
@@ -388,22 +517,35 @@ func Partition[A any](c <-chan A, predicate func(a A) bool, options ...Option) (
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
 
 	sat := make(chan A, s.buffer)
 	not := make(chan A, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(sat)
 		defer close(not)
 
-		for e := range c {
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
+			var ok bool
+			switch call(s, func() { ok = predicate(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
 			out := sat
-			if !predicate(e) {
+			if !ok {
 				out = not
 			}
-			select {
-			case <-s.done:
+			if !send(s, out, e) {
 				return
-			case out <- e:
 			}
 		}
 	}()
@@ -428,17 +570,30 @@ func TakeWhile[A any](c <-chan A, take func(a A) bool, options ...Option) <-chan
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
 	out := make(chan A, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(out)
-		for e := range c {
-			if !take(e) {
+		for {
+			e, more := receive(s, c)
+			if !more {
 				return
 			}
-			select {
-			case <-s.done:
+			var ok bool
+			switch call(s, func() { ok = take(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !ok {
+				return
+			}
+			if !send(s, out, e) {
 				return
-			case out <- e:
 			}
 
 		}
@@ -465,16 +620,20 @@ func Take[A any](c <-chan A, i int, options ...Option) <-chan A {
 	}
 	out := make(chan A, s.buffer)
 	go func() {
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(out)
 		if i < 1 {
 			return
 		}
 
-		for e := range c {
-			select {
-			case <-s.done:
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
+			if !send(s, out, e) {
 				return
-			case out <- e:
 			}
 			i -= 1
 			if i == 0 {
@@ -505,17 +664,21 @@ func Drop[A any](c <-chan A, i int, options ...Option) <-chan A {
 
 	out := make(chan A, s.buffer)
 	go func() {
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(out)
-		for e := range c {
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
 			if i > 0 {
 				i -= 1
 				continue
 			}
 
-			select {
-			case <-s.done:
+			if !send(s, out, e) {
 				return
-			case out <- e:
 			}
 
 		}
@@ -540,20 +703,35 @@ func DropWhile[A any](c <-chan A, drop func(a A) bool, options ...Option) <-chan
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
 
 	out := make(chan A, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
 		defer close(out)
 		var dropping = true
-		for e := range c {
-			if dropping && drop(e) {
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
+			if dropping {
+				switch call(s, func() { dropping = drop(e) }) {
+				case callSkip:
+					dropping = true
+					continue
+				case callStop:
+					return
+				}
+			}
+			if dropping {
 				continue
 			}
 			dropping = false
-			select {
-			case <-s.done:
+			if !send(s, out, e) {
 				return
-			case out <- e:
 			}
 		}
 	}()
@@ -577,19 +755,33 @@ func Zip[A any, B any, C any](ac <-chan A, bc <-chan B, zipper func(a A, b B) C,
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
 
 	out := make(chan C, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, bc)
+		defer drain(s, ac)
+		defer observeClosed(s)
 		defer close(out)
-		for a := range ac {
-			b, ok := <-bc
+		for {
+			a, more := receive(s, ac)
+			if !more {
+				return
+			}
+			b, ok := receive(s, bc)
 			if !ok {
 				return
 			}
-			select {
-			case <-s.done:
+			var c C
+			switch call(s, func() { c = zipper(a, b) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, out, c) {
 				return
-			case out <- zipper(a, b):
 			}
 		}
 	}()
@@ -604,19 +796,34 @@ func Unzip[A any, B any, C any](zipped <-chan C, unzipper func(c C) (A, B), opti
 	for _, o := range options {
 		s = o(s)
 	}
+	s = stoppable(s)
 
 	ac := make(chan A, s.buffer)
 	bc := make(chan B, s.buffer)
 	go func() {
+		defer recoverStage(s)
+		defer drain(s, zipped)
+		defer observeClosed(s)
 		defer close(ac)
 		defer close(bc)
-		for c := range zipped {
-			a, b := unzipper(c)
-			select {
-			case <-s.done:
+		for {
+			c, more := receive(s, zipped)
+			if !more {
+				return
+			}
+			var a A
+			var b B
+			switch call(s, func() { a, b = unzipper(c) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, ac, a) {
+				return
+			}
+			if !send(s, bc, b) {
 				return
-			case ac <- a:
-				bc <- b
 			}
 		}
 	}()
@@ -631,15 +838,16 @@ func Collect[A any](c <-chan A, options ...Option) []A {
 		s = o(s)
 	}
 	var out []A
-	for val := range c {
-		out = append(out, val)
-		select {
-		case <-s.done:
+	for {
+		val, more := receive(s, c)
+		if !more {
+			if s.drain {
+				go drain(s, c)
+			}
 			return out
-		default:
 		}
+		out = append(out, val)
 	}
-	return out
 }
 
 // DropAll will consume a channel until it closes. If async is false, it will block until the channel is closed and all entries are consumed.
@@ -708,7 +916,16 @@ func Buffer[A any](size int, in <-chan A, options ...Option) ([]A, bool) {
 
 	for {
 
-		val, more := <-in
+		var val A
+		var more bool
+		select {
+		case <-s.done:
+			if s.drain {
+				go drain(s, in)
+			}
+			return out, true
+		case val, more = <-in:
+		}
 		out = append(out, val)
 
 		if !more {
@@ -179,6 +179,27 @@ func TestFanOut(t *testing.T) {
 
 }
 
+func TestFanOutDone(t *testing.T) {
+	done := make(chan struct{})
+	outs := FanOut(Generate(1, 2, 3), 2, OpDone(done))
+
+	<-outs[0] // outs[1] is never read, so FanOut is blocked writing to it
+	close(done)
+
+	closed := make(chan struct{})
+	go func() {
+		DropAll(outs[0], false)
+		DropAll(outs[1], false)
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Log("expected FanOut to close its outputs once done")
+		t.Fail()
+	}
+}
+
 func TestCompact(t *testing.T) {
 
 	c := Compact(Generate(1, 2, 3, 4, 5, 5, 5, 6, 7, 7, 7, 8, 8, 8, 9), compare.Equal[int])
@@ -0,0 +1,74 @@
+package chanz
+
+import "time"
+
+// Clock is the source of time used by the time based stages, such as Batch. The default is the system clock, but it
+// can be replaced using OpClock, which is mostly useful for making tests deterministic
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) ClockTimer
+	NewTicker(d time.Duration) ClockTicker
+}
+
+// ClockTimer is the equivalent of a time.Timer, created by a Clock
+type ClockTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// ClockTicker is the equivalent of a time.Ticker, created by a Clock
+type ClockTicker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// OpClock sets the Clock used by time based stages. Default is the system clock
+func OpClock(clock Clock) Option {
+	return func(s settings) settings {
+		s.clock = clock
+		return s
+	}
+}
+
+// SystemClock returns a Clock backed by the time package
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+func clockOf(s settings) Clock {
+	if s.clock == nil {
+		return systemClock{}
+	}
+	return s.clock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) ClockTimer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) NewTicker(d time.Duration) ClockTicker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
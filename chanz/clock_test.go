@@ -0,0 +1,163 @@
+package chanz
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when Advance is called. Unlike the system clock, Advance waits for every timer
+// and ticker that fires to be received, or stopped, before moving on, which lets tests know that a stage has seen a tick
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers map[*fakeTimer]struct{}
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:    time.Unix(0, 0),
+		timers: map[*fakeTimer]struct{}{},
+	}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) ClockTimer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) ClockTicker {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// Advance moves the clock forward, firing every timer and ticker that is due on the way
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		var next *fakeTimer
+		for t := range f.timers {
+			if !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		f.now = next.when
+		gen := next.fire(f.now)
+
+		f.mu.Unlock()
+		next.awaitReceived(gen)
+		f.mu.Lock()
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+// BlockUntil waits until at least n timers or tickers are waiting on the clock
+func (f *fakeClock) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		l := len(f.timers)
+		f.mu.Unlock()
+		if l >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// BlockUntilDue waits until a timer or ticker is due at the given time
+func (f *fakeClock) BlockUntilDue(when time.Time) {
+	for {
+		f.mu.Lock()
+		var found bool
+		for t := range f.timers {
+			found = found || t.when.Equal(when)
+		}
+		f.mu.Unlock()
+		if found {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type fakeTimer struct {
+	clock  *fakeClock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+	gen    int
+}
+
+// fire must be called with the clock lock held, it returns the generation of the timer that fired
+func (t *fakeTimer) fire(now time.Time) int {
+	select {
+	case t.c <- now:
+	default:
+	}
+	if t.period > 0 {
+		t.when = t.when.Add(t.period)
+		return t.gen
+	}
+	delete(t.clock.timers, t)
+	return t.gen
+}
+
+// awaitReceived waits until the fired time has been received, or the timer has been stopped or reset
+func (t *fakeTimer) awaitReceived(gen int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		t.clock.mu.Lock()
+		waiting := len(t.c) > 0 && t.gen == gen
+		t.clock.mu.Unlock()
+		if !waiting {
+			return
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	t.gen++
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, active := t.clock.timers[t]
+	t.gen++
+	t.when = t.clock.now.Add(d)
+	t.clock.timers[t] = struct{}{}
+	if d <= 0 && t.period == 0 {
+		t.fire(t.clock.now)
+	}
+	return active
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
@@ -0,0 +1,199 @@
+package chanz
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// histogramBuckets is the number of buckets in a Histogram. The upper bounds of the buckets doubles from 1µs, and the
+// last bucket holds everything above ~16s
+const histogramBuckets = 26
+
+// Bucket is one bucket of a Histogram, holding the number of durations that was at most UpperBound, but more than the
+// UpperBound of the previous bucket
+type Bucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// Histogram is a summary of durations, such as the time spent processing items in a stage
+type Histogram struct {
+	Count   int64
+	Sum     time.Duration
+	Max     time.Duration
+	Buckets []Bucket
+}
+
+// Mean returns the mean of the durations in the histogram, or 0 if it is empty
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an estimate of the q quantile, 0 <= q <= 1, of the durations in the histogram. The estimate is the
+// upper bound of the bucket the quantile falls into, but never more than Max.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen >= rank && b.UpperBound < h.Max {
+			return b.UpperBound
+		}
+		if seen >= rank {
+			break
+		}
+	}
+	return h.Max
+}
+
+type histogram struct {
+	count  int64
+	sum    time.Duration
+	max    time.Duration
+	counts [histogramBuckets]int64
+}
+
+func (h *histogram) add(d time.Duration) {
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+	i := 0
+	for bound := time.Microsecond; i < histogramBuckets-1 && d > bound; bound *= 2 {
+		i++
+	}
+	h.counts[i]++
+}
+
+func (h *histogram) snapshot() Histogram {
+	res := Histogram{
+		Count:   h.count,
+		Sum:     h.sum,
+		Max:     h.max,
+		Buckets: make([]Bucket, histogramBuckets),
+	}
+	bound := time.Microsecond
+	for i := range res.Buckets {
+		res.Buckets[i] = Bucket{UpperBound: bound, Count: h.counts[i]}
+		bound *= 2
+	}
+	res.Buckets[histogramBuckets-1].UpperBound = math.MaxInt64
+	return res
+}
+
+// StageStats is what a Collector knows about a stage
+type StageStats struct {
+	Received   int64
+	Emitted    int64
+	Closed     int64
+	Processing Histogram
+	Blocked    Histogram
+}
+
+type stageStats struct {
+	received   int64
+	emitted    int64
+	closed     int64
+	processing histogram
+	blocked    histogram
+}
+
+// Collector is an Observer that keeps counters and latency histograms, per stage name, in memory
+type Collector struct {
+	mu     sync.Mutex
+	stages map[string]*stageStats
+}
+
+// NewCollector returns an empty Collector, to be supplied to stages with OpObserver
+func NewCollector() *Collector {
+	return &Collector{stages: map[string]*stageStats{}}
+}
+
+func (c *Collector) stage(name string) *stageStats {
+	s, ok := c.stages[name]
+	if !ok {
+		s = &stageStats{}
+		c.stages[name] = s
+	}
+	return s
+}
+
+func (c *Collector) Received(stage string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stage(stage).received++
+}
+
+func (c *Collector) Emitted(stage string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stage(stage).emitted++
+}
+
+func (c *Collector) Processed(stage string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stage(stage).processing.add(d)
+}
+
+func (c *Collector) Blocked(stage string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stage(stage).blocked.add(d)
+}
+
+func (c *Collector) Closed(stage string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stage(stage).closed++
+}
+
+// Stage returns the stats of the stage with the given name
+func (c *Collector) Stage(name string) StageStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.stages[name]
+	if !ok {
+		return StageStats{}
+	}
+	return s.snapshot()
+}
+
+// Stats returns the stats of every stage that has been observed, by name
+func (c *Collector) Stats() map[string]StageStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make(map[string]StageStats, len(c.stages))
+	for name, s := range c.stages {
+		res[name] = s.snapshot()
+	}
+	return res
+}
+
+// Reset removes everything the Collector has collected so far
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = map[string]*stageStats{}
+}
+
+func (s *stageStats) snapshot() StageStats {
+	return StageStats{
+		Received:   s.received,
+		Emitted:    s.emitted,
+		Closed:     s.closed,
+		Processing: s.processing.snapshot(),
+		Blocked:    s.blocked.snapshot(),
+	}
+}
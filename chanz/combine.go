@@ -0,0 +1,158 @@
+package chanz
+
+// CombineLatest takes two chans and returns a chan. Every time an item is read from either ac or bc, the combiner is
+// applied to it and the latest item read from the other chan, and the result is written onto the returning chan.
+// Nothing is written until at least one item has been read from each chan.
+// e.g. a1, b1, b2, a2 gives combiner(a1, b1), combiner(a1, b2), combiner(a2, b2)
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once both "ac" and "bc", "done" channel is closed or the context.Done is closed, which is supplied in
+// Option. It will also stop if either "ac" or "bc" is closed before any item has been read from it.
+func CombineLatest[A any, B any, C any](ac <-chan A, bc <-chan B, combiner func(a A, b B) C, options ...Option) <-chan C {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	out := make(chan C, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, bc)
+		defer drain(s, ac)
+		defer observeClosed(s)
+		defer close(out)
+
+		var a A
+		var b B
+		var hasA, hasB bool
+		ain, bin := ac, bc
+		for ain != nil || bin != nil {
+			select {
+			case <-s.done:
+				return
+			case e, ok := <-ain:
+				if !ok {
+					if !hasA {
+						return
+					}
+					ain = nil
+					continue
+				}
+				observeReceived(s)
+				a, hasA = e, true
+			case e, ok := <-bin:
+				if !ok {
+					if !hasB {
+						return
+					}
+					bin = nil
+					continue
+				}
+				observeReceived(s)
+				b, hasB = e, true
+			}
+			if !hasA || !hasB {
+				continue
+			}
+
+			var c C
+			switch call(s, func() { c = combiner(a, b) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, out, c) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// CombineLatestWith takes two chans and returns a chan. Every time an item is read from either ac or bc, the combiner
+// is applied to it and the latest item read from the other chan, and the result is written onto the returning chan.
+// Nothing is written until at least one item has been read from each chan.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once both "ac" and "bc", "done" channel is closed or the context.Done is closed, which is supplied in
+// Option. It will also stop if either "ac" or "bc" is closed before any item has been read from it.
+func CombineLatestWith[A any, B any, C any](options ...Option) func(ac <-chan A, bc <-chan B, combiner func(a A, b B) C) <-chan C {
+	return func(ac <-chan A, bc <-chan B, combiner func(a A, b B) C) <-chan C {
+		return CombineLatest(ac, bc, combiner, options...)
+	}
+}
+
+// ZipLongest takes two chans and returns a chan. Like Zip, it reads an A item and a B item, applies the zipper to them
+// and writes the result onto the returning chan. Unlike Zip, it keeps going until both chans are closed, using fillA in
+// place of A items once ac is closed, and fillB in place of B items once bc is closed.
+// e.g. a1, a2, a3 and b1 gives zipper(a1, b1), zipper(a2, fillB), zipper(a3, fillB)
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once both "ac" and "bc", "done" channel is closed or the context.Done is closed, which is supplied in
+// Option
+func ZipLongest[A any, B any, C any](ac <-chan A, bc <-chan B, zipper func(a A, b B) C, fillA A, fillB B, options ...Option) <-chan C {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	out := make(chan C, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, bc)
+		defer drain(s, ac)
+		defer observeClosed(s)
+		defer close(out)
+
+		aOpen, bOpen := true, true
+		for {
+			a, b := fillA, fillB
+			if aOpen {
+				var e A
+				e, aOpen = receive(s, ac)
+				if aOpen {
+					a = e
+				}
+			}
+			if bOpen {
+				var e B
+				e, bOpen = receive(s, bc)
+				if bOpen {
+					b = e
+				}
+			}
+			select {
+			case <-s.done: // receive reports a closed chan when done is closed
+				return
+			default:
+			}
+			if !aOpen && !bOpen {
+				return
+			}
+
+			var c C
+			switch call(s, func() { c = zipper(a, b) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, out, c) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ZipLongestWith takes two chans and returns a chan. Like Zip, it reads an A item and a B item, applies the zipper to
+// them and writes the result onto the returning chan. Unlike Zip, it keeps going until both chans are closed, using
+// fillA in place of A items once ac is closed, and fillB in place of B items once bc is closed.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once both "ac" and "bc", "done" channel is closed or the context.Done is closed, which is supplied in
+// Option
+func ZipLongestWith[A any, B any, C any](options ...Option) func(ac <-chan A, bc <-chan B, zipper func(a A, b B) C, fillA A, fillB B) <-chan C {
+	return func(ac <-chan A, bc <-chan B, zipper func(a A, b B) C, fillA A, fillB B) <-chan C {
+		return ZipLongest(ac, bc, zipper, fillA, fillB, options...)
+	}
+}
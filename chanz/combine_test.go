@@ -0,0 +1,106 @@
+package chanz
+
+import (
+	"fmt"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+func concat(a int, b string) string {
+	return fmt.Sprintf("%d%s", a, b)
+}
+
+func TestCombineLatest(t *testing.T) {
+	ac, bc := make(chan int), make(chan string)
+	out := CombineLatest(ac, bc, concat)
+
+	var res []string
+	ac <- 1
+	bc <- "a"
+	res = append(res, <-out)
+	bc <- "b"
+	res = append(res, <-out)
+	ac <- 2
+	res = append(res, <-out)
+	close(ac)
+	bc <- "c"
+	res = append(res, <-out)
+	close(bc)
+	res = append(res, Collect(out)...)
+
+	exp := []string{"1a", "1b", "2b", "2c"}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestCombineLatestEmptySide(t *testing.T) {
+	res := Collect(CombineLatest(Generate(1, 2, 3), Generate[string](), concat))
+	if len(res) != 0 {
+		t.Logf("expected nothing, but got %v", res)
+		t.Fail()
+	}
+}
+
+func TestCombineLatestDone(t *testing.T) {
+	done := make(chan struct{})
+	out := CombineLatest(make(chan int), make(chan string), concat, OpDone(done))
+	close(done)
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Log("expected out to be closed")
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Log("expected out to be closed by now")
+		t.Fail()
+	}
+}
+
+func TestZipLongest(t *testing.T) {
+	res := Collect(ZipLongest(Generate(1, 2, 3), Generate("a"), concat, 0, "-"))
+	exp := []string{"1a", "2-", "3-"}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	res = Collect(ZipLongest(Generate(1), Generate("a", "b", "c"), concat, 0, "-"))
+	exp = []string{"1a", "0b", "0c"}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestZipDoneWaitingOnB(t *testing.T) {
+	for name, zip := range map[string]func(ac <-chan int, bc <-chan string, done <-chan struct{}) <-chan string{
+		"Zip": func(ac <-chan int, bc <-chan string, done <-chan struct{}) <-chan string {
+			return Zip(ac, bc, concat, OpDone(done))
+		},
+		"ZipLongest": func(ac <-chan int, bc <-chan string, done <-chan struct{}) <-chan string {
+			return ZipLongest(ac, bc, concat, 0, "", OpDone(done))
+		},
+	} {
+		ac := make(chan int)
+		done := make(chan struct{})
+		out := zip(ac, make(chan string), done)
+		ac <- 1 // The stage is now waiting on bc
+		close(done)
+
+		select {
+		case _, ok := <-out:
+			if ok {
+				t.Logf("expected out of %s to be closed", name)
+				t.Fail()
+			}
+		case <-time.After(time.Second):
+			t.Logf("expected out of %s to be closed by now", name)
+			t.Fail()
+		}
+	}
+}
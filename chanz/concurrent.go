@@ -0,0 +1,148 @@
+package chanz
+
+import "sync"
+
+// mapConcurrent reads from in and applies fn using up to s.workers goroutines. If fn returns false as its second
+// return value, the result is discarded.
+// Unless s.unordered is set, results are emitted in the order they were read from in. The reorder buffer is bounded by
+// the number of workers, so a slow item will hold back at most s.workers finished items before reading is paused.
+func mapConcurrent[A any, B any](in <-chan A, fn func(a A) (B, bool), s settings) <-chan B {
+	// quit lets a worker that panics shut down the whole stage
+	quit := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(quit) })
+	}
+	s.done = SomeDone(s.done, quit)
+
+	apply := func(e A) (val B, keep bool, ok bool) {
+		switch call(s, func() { val, keep = fn(e) }) {
+		case callSkip:
+			return val, false, true
+		case callStop:
+			return val, false, false
+		}
+		return val, keep, true
+	}
+
+	if s.unordered {
+		return mapUnordered(in, apply, stop, s)
+	}
+
+	type result struct {
+		val  B
+		keep bool
+		ok   bool
+	}
+
+	out := make(chan B, s.buffer)
+	pending := make(chan chan result, s.workers)
+	sem := make(chan struct{}, s.workers)
+
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer close(pending)
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+
+			select {
+			case <-s.done:
+				return
+			case sem <- struct{}{}:
+			}
+
+			res := make(chan result, 1)
+			select {
+			case <-s.done:
+				return
+			case pending <- res:
+			}
+
+			go func(e A) {
+				defer func() { <-sem }()
+				val, keep, ok := apply(e)
+				res <- result{val: val, keep: keep, ok: ok}
+			}(e)
+		}
+	}()
+
+	go func() {
+		defer stop()
+		defer observeClosed(s)
+		defer close(out)
+		for {
+			var res chan result
+			var ok bool
+			select {
+			case <-s.done:
+				return
+			case res, ok = <-pending:
+				if !ok {
+					return
+				}
+			}
+
+			var r result
+			select {
+			case <-s.done:
+				return
+			case r = <-res:
+			}
+			if !r.ok {
+				return
+			}
+			if !r.keep {
+				continue
+			}
+			if !send(s, out, r.val) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func mapUnordered[A any, B any](in <-chan A, apply func(e A) (B, bool, bool), stop func(), s settings) <-chan B {
+	var wg sync.WaitGroup
+	out := make(chan B, s.buffer)
+
+	worker := func() {
+		defer wg.Done()
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+
+			val, keep, ok := apply(e)
+			if !ok {
+				stop()
+				return
+			}
+			if !keep {
+				continue
+			}
+			if !send(s, out, val) {
+				return
+			}
+		}
+	}
+
+	wg.Add(s.workers)
+	for i := 0; i < s.workers; i++ {
+		go worker()
+	}
+
+	go func() {
+		wg.Wait()
+		stop()
+		close(out)
+		observeClosed(s)
+		drain(s, in)
+	}()
+	return out
+}
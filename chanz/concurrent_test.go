@@ -0,0 +1,101 @@
+package chanz
+
+import (
+	"math/rand"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modfin/henry/slicez"
+)
+
+func TestMapWorkersOrdered(t *testing.T) {
+	var running, maxRunning int32
+	in := make([]int, 50)
+	for i := range in {
+		in[i] = i
+	}
+
+	mapped := Map(Generate(in...), func(a int) int {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if cur <= m || atomic.CompareAndSwapInt32(&maxRunning, m, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return a * 2
+	}, OpWorkers(4))
+
+	res := Collect(mapped)
+	exp := slicez.Map(in, func(a int) int { return a * 2 })
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if maxRunning > 4 {
+		t.Logf("expected at most 4 concurrent mappers, but got %d", maxRunning)
+		t.Fail()
+	}
+}
+
+func TestMapWorkersUnordered(t *testing.T) {
+	in := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	mapped := Map(Generate(in...), func(a int) int {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+		return a
+	}, OpWorkers(3), OpUnordered())
+
+	res := Collect(mapped)
+	sort.Ints(res)
+	if !slicez.Equal(res, in) {
+		t.Logf("expected, %v, but got %v", in, res)
+		t.Fail()
+	}
+}
+
+func TestFilterWorkers(t *testing.T) {
+	f := Filter(Generate(1, 2, 3, 4, 5, 6, 7, 8), func(a int) bool {
+		return a%2 == 0
+	}, OpWorkers(3))
+
+	res := Collect(f)
+	exp := []int{2, 4, 6, 8}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestMapWorkersDone(t *testing.T) {
+	done := make(chan struct{})
+	in := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case in <- i:
+			}
+		}
+	}()
+
+	mapped := Map(in, func(a int) int { return a }, OpWorkers(4), OpDone(done))
+	<-mapped
+	close(done)
+
+	closed := make(chan struct{})
+	go func() {
+		DropAll(mapped, false)
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Log("expected mapped chan to be closed by now")
+		t.Fail()
+	}
+}
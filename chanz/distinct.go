@@ -0,0 +1,137 @@
+package chanz
+
+import (
+	"container/list"
+	"time"
+)
+
+// DistinctStats is what Distinct and DistinctWindow report to the callback supplied by OpDistinctStats
+type DistinctStats struct {
+	Keys    int   // Keys currently remembered
+	Hits    int64 // Items dropped, since their key has been seen before
+	Misses  int64 // Items written, since their key has not been seen before
+	Evicted int64 // Keys forgotten to stay within maxKeys
+	Expired int64 // Keys forgotten since they have not been seen for ttl
+}
+
+// OpDistinctStats sets a func that Distinct and DistinctWindow calls with their stats so far, every time an item is
+// read. It is called from the goroutine of the stage, so it should return quickly.
+func OpDistinctStats(fn func(stats DistinctStats)) Option {
+	return func(s settings) settings {
+		s.distinctStats = fn
+		return s
+	}
+}
+
+type seenKey[K comparable] struct {
+	key  K
+	seen time.Time
+}
+
+// Distinct takes a chan and writes every item onto the return chan, unless an item with the same key has been
+// written before. Every key is remembered for as long as the stage runs, see DistinctWindow to bound the memory used.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Distinct[A any, K comparable](c <-chan A, key func(a A) K, options ...Option) <-chan A {
+	return DistinctWindow(c, key, 0, 0, options...)
+}
+
+// DistinctWith takes a chan and writes every item onto the return chan, unless an item with the same key has been
+// written before. Every key is remembered for as long as the stage runs, see DistinctWindow to bound the memory used.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func DistinctWith[A any, K comparable](options ...Option) func(c <-chan A, key func(a A) K) <-chan A {
+	return func(c <-chan A, key func(a A) K) <-chan A {
+		return Distinct(c, key, options...)
+	}
+}
+
+// DistinctWindow takes a chan and writes every item onto the return chan, unless an item with the same key has been
+// seen recently. At most maxKeys keys are remembered, and once there are more, the least recently seen key is
+// forgotten. A key that has not been seen for ttl is forgotten as well. A maxKeys or ttl of 0 means no limit.
+// e.g. maxKeys 2 on a, b, a, c, b gives a, b, c, b, since b is forgotten when c is seen
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func DistinctWindow[A any, K comparable](c <-chan A, key func(a A) K, maxKeys int, ttl time.Duration, options ...Option) <-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	clock := clockOf(s)
+	out := make(chan A, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
+		defer close(out)
+
+		var stats DistinctStats
+		lru := list.New() // The most recently seen key is at the front
+		keys := map[K]*list.Element{}
+		forget := func(el *list.Element) {
+			delete(keys, lru.Remove(el).(seenKey[K]).key)
+		}
+
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
+			var k K
+			switch call(s, func() { k = key(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+
+			var now time.Time
+			if ttl > 0 {
+				now = clock.Now()
+				for el := lru.Back(); el != nil && !el.Value.(seenKey[K]).seen.Add(ttl).After(now); el = lru.Back() {
+					forget(el)
+					stats.Expired++
+				}
+			}
+
+			el, hit := keys[k]
+			if hit {
+				el.Value = seenKey[K]{key: k, seen: now}
+				lru.MoveToFront(el)
+				stats.Hits++
+			} else {
+				keys[k] = lru.PushFront(seenKey[K]{key: k, seen: now})
+				stats.Misses++
+				if maxKeys > 0 && lru.Len() > maxKeys {
+					forget(lru.Back())
+					stats.Evicted++
+				}
+			}
+
+			if s.distinctStats != nil {
+				stats.Keys = lru.Len()
+				s.distinctStats(stats)
+			}
+			if hit {
+				continue
+			}
+			if !send(s, out, e) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// DistinctWindowWith takes a chan and writes every item onto the return chan, unless an item with the same key has
+// been seen recently. At most maxKeys keys are remembered, and once there are more, the least recently seen key is
+// forgotten. A key that has not been seen for ttl is forgotten as well. A maxKeys or ttl of 0 means no limit.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func DistinctWindowWith[A any, K comparable](options ...Option) func(c <-chan A, key func(a A) K, maxKeys int, ttl time.Duration) <-chan A {
+	return func(c <-chan A, key func(a A) K, maxKeys int, ttl time.Duration) <-chan A {
+		return DistinctWindow(c, key, maxKeys, ttl, options...)
+	}
+}
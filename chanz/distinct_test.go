@@ -0,0 +1,94 @@
+package chanz
+
+import (
+	"context"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+func TestDistinct(t *testing.T) {
+	res := Collect(Distinct(Generate(1, 2, 1, 3, 2, 4, 1), func(a int) int { return a }))
+	exp := []int{1, 2, 3, 4}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestDistinctWindowMaxKeys(t *testing.T) {
+	var stats DistinctStats
+	res := Collect(DistinctWindow(Generate("a", "b", "a", "c", "b"), func(a string) string { return a }, 2, 0,
+		OpDistinctStats(func(s DistinctStats) { stats = s })))
+
+	exp := []string{"a", "b", "c", "b"}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	expStats := DistinctStats{Keys: 2, Hits: 1, Misses: 4, Evicted: 2}
+	if stats != expStats {
+		t.Logf("expected, %+v, but got %+v", expStats, stats)
+		t.Fail()
+	}
+}
+
+func TestDistinctWindowTTL(t *testing.T) {
+	clock := newFakeClock()
+	in := make(chan string)
+	stats := make(chan DistinctStats, 1)
+	out := DistinctWindow(in, func(a string) string { return a }, 0, time.Minute, OpClock(clock),
+		OpDistinctStats(func(s DistinctStats) { stats <- s }))
+
+	// Stats are reported once the stage is done with the clock, and before a new key is written
+	var res []string
+	var last DistinctStats
+	push := func(e string) {
+		in <- e
+		prev := last
+		last = <-stats
+		if last.Misses > prev.Misses {
+			res = append(res, <-out)
+		}
+	}
+
+	push("a")
+	clock.Advance(30 * time.Second)
+	push("a") // Seen 30s ago, dropped, and remembered for another minute
+	clock.Advance(50 * time.Second)
+	push("a") // Seen 50s ago, dropped
+	clock.Advance(time.Minute)
+	push("a") // Not seen for a minute, written
+	close(in)
+
+	exp := []string{"a", "a"}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if last.Expired != 1 || last.Hits != 2 || last.Misses != 2 {
+		t.Logf("expected 1 expired, 2 hits and 2 misses, but got %+v", last)
+		t.Fail()
+	}
+}
+
+func TestDistinctWindowCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := DistinctWindow(in, func(a int) int { return a }, 10, time.Minute, OpContext(ctx))
+
+	in <- 1
+	<-out
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Log("expected out to be closed")
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Log("expected out to be closed by now")
+		t.Fail()
+	}
+}
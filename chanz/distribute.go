@@ -0,0 +1,113 @@
+package chanz
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DistributeStrategy decides which output chan Distribute writes an item to
+type DistributeStrategy int
+
+const (
+	DistributeRoundRobin DistributeStrategy = iota
+	DistributeLeastLoaded
+	DistributeFirstFree
+)
+
+// Distribute returns a slice of n chans and writes every item read from the input chan to exactly one of them.
+// Which chan is decided by the strategy:
+// DistributeRoundRobin writes to each chan in turn.
+// DistributeLeastLoaded writes to the chan with the fewest buffered items, ties are broken in turn, so it is
+// only different from round-robin if OpBuffer is supplied.
+// DistributeFirstFree writes to the first chan that has room for the item, or a reader waiting for it, meaning that
+// idle readers will take over work from busy ones. Each chan may hold back one item while waiting.
+// If n is 0, every item is read and discarded, and a negative n panics.
+// The return chans has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Distribute[A any](in <-chan A, n int, strategy DistributeStrategy, options ...Option) []<-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	if n < 0 {
+		panic(fmt.Sprintf("chanz: Distribute is given %d chans, but n can not be negative", n))
+	}
+
+	outs := make([]chan A, n)
+	for i := range outs {
+		outs[i] = make(chan A, s.buffer)
+	}
+
+	if strategy == DistributeFirstFree && n > 0 {
+		var wg sync.WaitGroup
+		forward := func(out chan A) {
+			defer drain(s, in)
+			defer wg.Done()
+			defer close(out)
+			for {
+				e, more := receive(s, in)
+				if !more {
+					return
+				}
+				if !send(s, out, e) {
+					return
+				}
+			}
+		}
+		wg.Add(len(outs))
+		for _, out := range outs {
+			go forward(out)
+		}
+		go func() {
+			wg.Wait()
+			observeClosed(s)
+		}()
+		return Readers(outs...)
+	}
+
+	go func() {
+		defer drain(s, in)
+		defer func() {
+			for _, o := range outs {
+				close(o)
+			}
+			observeClosed(s)
+		}()
+
+		var next int
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			if n == 0 {
+				continue
+			}
+			target := next
+			if strategy == DistributeLeastLoaded {
+				for i := 0; i < n; i++ {
+					j := (next + i) % n
+					if len(outs[j]) < len(outs[target]) {
+						target = j
+					}
+				}
+			}
+			next = (target + 1) % n
+
+			if !send(s, outs[target], e) {
+				return
+			}
+		}
+	}()
+	return Readers(outs...)
+}
+
+// DistributeWith returns a slice of n chans and writes every item read from the input chan to exactly one of them.
+// Which chan is decided by the strategy, DistributeRoundRobin, DistributeLeastLoaded or DistributeFirstFree.
+// The return chans has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func DistributeWith[A any](options ...Option) func(in <-chan A, n int, strategy DistributeStrategy) []<-chan A {
+	return func(in <-chan A, n int, strategy DistributeStrategy) []<-chan A {
+		return Distribute(in, n, strategy, options...)
+	}
+}
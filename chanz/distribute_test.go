@@ -0,0 +1,136 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/slicez"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func collectAll[A any](cs []<-chan A) [][]A {
+	var wg sync.WaitGroup
+	res := make([][]A, len(cs))
+	for i, c := range cs {
+		wg.Add(1)
+		go func(i int, c <-chan A) {
+			defer wg.Done()
+			res[i] = Collect(c)
+		}(i, c)
+	}
+	wg.Wait()
+	return res
+}
+
+func TestDistributeRoundRobin(t *testing.T) {
+	outs := Distribute(Generate(1, 2, 3, 4, 5, 6, 7), 3, DistributeRoundRobin)
+	res := collectAll(outs)
+	exp := [][]int{{1, 4, 7}, {2, 5}, {3, 6}}
+	if !slicez.EqualBy(res, exp, slicez.Equal[int]) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+// emitted is an Observer that signals every item written by a stage, and blocks the stage until it is read
+type emitted chan struct{}
+
+func (e emitted) Received(string)                 {}
+func (e emitted) Emitted(string)                  { e <- struct{}{} }
+func (e emitted) Processed(string, time.Duration) {}
+func (e emitted) Blocked(string, time.Duration)   {}
+func (e emitted) Closed(string)                   {}
+
+func TestDistributeLeastLoaded(t *testing.T) {
+	in := make(chan int)
+	placed := make(emitted)
+	outs := Distribute(in, 2, DistributeLeastLoaded, OpBuffer(10), OpObserver(placed))
+	push := func(items ...int) {
+		for _, e := range items {
+			in <- e
+			<-placed
+		}
+	}
+
+	push(1, 2, 3, 4)
+	<-outs[0]
+	<-outs[0]
+	push(5, 6, 7) // 6 is written to the first chan, since it has fewer items, where round-robin would pick the second
+	close(in)
+
+	res := collectAll(outs)
+	exp := [][]int{{5, 6}, {2, 4, 7}}
+	if !slicez.EqualBy(res, exp, slicez.Equal[int]) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestDistributeFirstFree(t *testing.T) {
+	outs := Distribute(Generate(1, 2, 3, 4, 5, 6, 7, 8, 9), 3, DistributeFirstFree)
+
+	// The first reader is stuck after its first item, until released, so the others must take over its work
+	release := make(chan struct{})
+	took := make(chan struct{}, 9)
+	res := make([][]int, len(outs))
+	var wg sync.WaitGroup
+	for i, o := range outs {
+		wg.Add(1)
+		go func(i int, o <-chan int) {
+			defer wg.Done()
+			for e := range o {
+				res[i] = append(res[i], e)
+				if i == 0 {
+					<-release
+					continue
+				}
+				took <- struct{}{}
+			}
+		}(i, o)
+	}
+
+	for i := 0; i < 7; i++ { // The stuck reader has read one item, and may hold back one more
+		select {
+		case <-took:
+		case <-time.After(time.Second):
+			t.Logf("expected the free readers to take 7 items, but they took %d", i)
+			t.Fail()
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if len(res[0]) > 2 {
+		t.Logf("expected the stuck reader to get at most 2 items, but got %v", res[0])
+		t.Fail()
+	}
+	all := append(append(append([]int{}, res[0]...), res[1]...), res[2]...)
+	sort.Ints(all)
+	exp := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	if !slicez.Equal(all, exp) {
+		t.Logf("expected, %v, but got %v", exp, all)
+		t.Fail()
+	}
+}
+
+func TestDistributeNone(t *testing.T) {
+	in, finished := unbuffered(10)
+	if outs := Distribute(in, 0, DistributeFirstFree); len(outs) != 0 {
+		t.Logf("expected no chans, but got %d", len(outs))
+		t.Fail()
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Log("expected in to be read until it is closed")
+		t.Fail()
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Log("expected a panic for a negative number of chans")
+			t.Fail()
+		}
+	}()
+	Distribute(Generate(1), -1, DistributeRoundRobin)
+}
@@ -0,0 +1,286 @@
+package chanz
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrOverflow is reported by a stage using OverflowError when its buffer is full
+var ErrOverflow = errors.New("chanz: buffer overflow")
+
+// minRing is the smallest capacity of the ring buffer used by Elastic
+const minRing = 16
+
+// ring is a FIFO queue backed by a slice that grows when full and shrinks when mostly empty
+type ring[A any] struct {
+	buf  []A
+	head int
+	n    int
+	min  int
+}
+
+func (r *ring[A]) len() int {
+	return r.n
+}
+
+func (r *ring[A]) push(a A) {
+	if r.n == len(r.buf) {
+		size := len(r.buf) * 2
+		if size < minRing {
+			size = minRing
+		}
+		r.resize(size)
+	}
+	r.buf[(r.head+r.n)%len(r.buf)] = a
+	r.n++
+}
+
+func (r *ring[A]) peek() A {
+	return r.buf[r.head]
+}
+
+func (r *ring[A]) pop() A {
+	var zero A
+	a := r.buf[r.head]
+	r.buf[r.head] = zero // Lets the item be garbage collected
+	r.head = (r.head + 1) % len(r.buf)
+	r.n--
+
+	// Memory is given back once the buffer is mostly empty, but never below min, see OpElasticReserve
+	if half := len(r.buf) / 2; r.n <= len(r.buf)/4 && half >= minRing && half >= r.min {
+		r.resize(half)
+	}
+	return a
+}
+
+func (r *ring[A]) resize(size int) {
+	buf := make([]A, size)
+	for i := 0; i < r.n; i++ {
+		buf[i] = r.buf[(r.head+i)%len(r.buf)]
+	}
+	r.buf, r.head = buf, 0
+}
+
+// ElasticStats is kept up to date by an Elastic stage supplied with OpElasticStats. It is safe to read concurrently.
+type ElasticStats struct {
+	length    int64
+	highWater int64
+	dropped   int64
+	overSoft  int32
+	err       atomic.Value
+}
+
+// Len returns the number of items currently buffered
+func (e *ElasticStats) Len() int {
+	return int(atomic.LoadInt64(&e.length))
+}
+
+// HighWater returns the largest number of items that has been buffered at once
+func (e *ElasticStats) HighWater() int {
+	return int(atomic.LoadInt64(&e.highWater))
+}
+
+// Dropped returns the number of items dropped because of OverflowDropNewest or OverflowDropOldest
+func (e *ElasticStats) Dropped() int {
+	return int(atomic.LoadInt64(&e.dropped))
+}
+
+// OverSoftCap returns true if more items than the soft cap, set by OpElasticSoftCap, are currently buffered
+func (e *ElasticStats) OverSoftCap() bool {
+	return atomic.LoadInt32(&e.overSoft) == 1
+}
+
+// Err returns ErrOverflow if the stage has stopped because of OverflowError, and nil otherwise
+func (e *ElasticStats) Err() error {
+	err, _ := e.err.Load().(error)
+	return err
+}
+
+func (e *ElasticStats) setLen(n int) {
+	if e == nil {
+		return
+	}
+	atomic.StoreInt64(&e.length, int64(n))
+	if int64(n) > atomic.LoadInt64(&e.highWater) {
+		atomic.StoreInt64(&e.highWater, int64(n))
+	}
+}
+
+func (e *ElasticStats) setOverSoftCap(over bool) {
+	if e == nil {
+		return
+	}
+	var v int32
+	if over {
+		v = 1
+	}
+	atomic.StoreInt32(&e.overSoft, v)
+}
+
+func (e *ElasticStats) drop() {
+	if e == nil {
+		return
+	}
+	atomic.AddInt64(&e.dropped, 1)
+}
+
+// OpElasticCap sets the largest number of items that Elastic buffers. Once n items are buffered, the policy set by
+// OpOverflow decides what happens to new items. Default is 0, meaning no cap.
+func OpElasticCap(n int) Option {
+	return func(s settings) settings {
+		s.hardCap = n
+		return s
+	}
+}
+
+// OpElasticSoftCap sets a soft cap on the number of items that Elastic buffers. Unlike OpElasticCap, items keep
+// flowing once it is crossed, but crossed, unless nil, is called with the number of items buffered every time the
+// buffer grows past n, and ElasticStats reports it until the buffer is back at n. Default is 0, meaning no soft cap.
+func OpElasticSoftCap(n int, crossed func(length int)) Option {
+	return func(s settings) settings {
+		s.softCap = n
+		s.softCapped = crossed
+		return s
+	}
+}
+
+// OpElasticReserve makes Elastic keep the memory for n items once its buffer has grown that large, rather than give
+// it back as the buffer empties. It does not limit the number of items buffered, see OpElasticCap for that.
+// Default is 0, meaning that memory is given back down to the minimum size of the buffer.
+func OpElasticReserve(n int) Option {
+	return func(s settings) settings {
+		s.reserve = n
+		return s
+	}
+}
+
+// OpElasticStats sets the ElasticStats that Elastic keeps up to date
+func OpElasticStats(stats *ElasticStats) Option {
+	return func(s settings) settings {
+		s.elasticStats = stats
+		return s
+	}
+}
+
+// Elastic reads items from "in" as fast as they are written and buffers them, in order, until they are read from the
+// return chan. Unlike OpBuffer, the buffer grows as needed, so bursty producers are never held back by slow consumers.
+// OpElasticSoftCap reports when the buffer grows past a soft cap, while items keep flowing.
+// The buffer can be capped by OpElasticCap, in which case OpOverflow decides what happens once it is full:
+// OverflowBlock stops reading from "in", OverflowDropNewest and OverflowDropOldest drops items, OverflowDisconnect
+// closes the return chan, and OverflowError closes the return chan and reports ErrOverflow. Elastic panics if the
+// policy is none of these.
+// The returned func waits for the stage to stop, and then returns ErrOverflow if it stopped because of OverflowError,
+// the context error, or ErrDone, if it stopped because of "done", and nil otherwise. ErrOverflow is also reported to
+// ElasticStats and to the Pipeline the stage is part of.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Elastic[A any](in <-chan A, options ...Option) (<-chan A, func() error) {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	switch s.overflow {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowDisconnect, OverflowError:
+	default:
+		panic(fmt.Sprintf("chanz: unknown overflow policy %d", s.overflow))
+	}
+
+	out := make(chan A, s.buffer)
+	finished := make(chan struct{})
+	var err error
+	go func() {
+		defer close(finished)
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+
+		stats := s.elasticStats
+		buf := &ring[A]{min: s.reserve}
+		// resized reports the length of the buffer, and if it has crossed the soft cap, once it has changed
+		var over bool
+		resized := func() {
+			stats.setLen(buf.len())
+			if s.softCap <= 0 || over == (buf.len() > s.softCap) {
+				return
+			}
+			over = !over
+			stats.setOverSoftCap(over)
+			if over && s.softCapped != nil {
+				s.softCapped(buf.len())
+			}
+		}
+		src := in
+		for {
+			reading := src
+			if s.hardCap > 0 && buf.len() >= s.hardCap && s.overflow == OverflowBlock {
+				reading = nil
+			}
+
+			var writing chan<- A
+			var next A
+			if buf.len() > 0 {
+				writing, next = out, buf.peek()
+			} else if src == nil {
+				return
+			}
+
+			select {
+			case <-s.done:
+				err = doneErr(s)
+				return
+			case e, ok := <-reading:
+				if !ok {
+					src = nil
+					continue
+				}
+				observeReceived(s)
+				if s.hardCap > 0 && buf.len() >= s.hardCap {
+					switch s.overflow {
+					case OverflowDropNewest:
+						stats.drop()
+						continue
+					case OverflowDropOldest:
+						buf.pop()
+						stats.drop()
+					case OverflowDisconnect:
+						return
+					case OverflowError:
+						err = ErrOverflow
+						if stats != nil {
+							stats.err.Store(ErrOverflow)
+						}
+						if s.fail != nil {
+							s.fail(ErrOverflow)
+						}
+						return
+					}
+				}
+				buf.push(e)
+				resized()
+			case writing <- next:
+				observeEmitted(s)
+				buf.pop()
+				resized()
+			}
+		}
+	}()
+	return out, func() error {
+		<-finished
+		return err
+	}
+}
+
+// ElasticWith reads items from "in" as fast as they are written and buffers them, in order, until they are read from
+// the return chan. Unlike OpBuffer, the buffer grows as needed, so bursty producers are never held back by slow
+// consumers. OpElasticSoftCap reports when the buffer grows past a soft cap, while items keep flowing. The buffer can
+// be capped by OpElasticCap, in which case OpOverflow decides what happens once it is full.
+// The returned func waits for the stage to stop, and then returns the error that stopped it, if any.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func ElasticWith[A any](options ...Option) func(in <-chan A) (<-chan A, func() error) {
+	return func(in <-chan A) (<-chan A, func() error) {
+		return Elastic(in, options...)
+	}
+}
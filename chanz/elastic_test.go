@@ -0,0 +1,205 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/slicez"
+	"sort"
+	"testing"
+	"time"
+)
+
+// produce writes items onto the return chan, and closes sent once they are all written
+func produce(items int) (<-chan int, <-chan struct{}) {
+	c := make(chan int)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		defer close(c)
+		for i := 0; i < items; i++ {
+			c <- i
+		}
+	}()
+	return c, sent
+}
+
+func waitFor(t *testing.T, c <-chan struct{}) {
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting")
+	}
+}
+
+func TestElastic(t *testing.T) {
+	in, sent := produce(1000)
+	var stats ElasticStats
+	out, _ := Elastic(in, OpElasticStats(&stats))
+
+	waitFor(t, sent) // The producer is never held back by the lack of a reader
+	for i := 0; i < 100 && stats.Len() < 1000; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if stats.Len() != 1000 || stats.HighWater() != 1000 {
+		t.Logf("expected 1000 buffered, but got %d with a high water mark of %d", stats.Len(), stats.HighWater())
+		t.Fail()
+	}
+
+	res := Collect(out)
+	if len(res) != 1000 || !sort.IntsAreSorted(res) {
+		t.Logf("expected 1000 items in order, but got %d", len(res))
+		t.Fail()
+	}
+	if stats.Len() != 0 || stats.HighWater() != 1000 {
+		t.Logf("expected 0 buffered, but got %d with a high water mark of %d", stats.Len(), stats.HighWater())
+		t.Fail()
+	}
+}
+
+func TestElasticSoftCap(t *testing.T) {
+	in := make(chan int)
+	crossed := make(chan int, 10)
+	var stats ElasticStats
+	out, _ := Elastic(in, OpElasticSoftCap(3, func(n int) { crossed <- n }), OpElasticStats(&stats))
+
+	for i := 1; i <= 5; i++ { // Items keep flowing past the soft cap
+		in <- i
+	}
+	if n := <-crossed; n != 4 {
+		t.Logf("expected, %v, but got %v", 4, n)
+		t.Fail()
+	}
+	if !stats.OverSoftCap() {
+		t.Log("expected the stats to report that the soft cap is crossed")
+		t.Fail()
+	}
+
+	close(in)
+	res := Collect(out)
+	exp := []int{1, 2, 3, 4, 5}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if stats.OverSoftCap() || len(crossed) != 0 {
+		t.Logf("expected the soft cap to be crossed once, and then no more, but got %d more", len(crossed))
+		t.Fail()
+	}
+}
+
+func TestElasticDropNewest(t *testing.T) {
+	in, sent := produce(10)
+	var stats ElasticStats
+	out, _ := Elastic(in, OpElasticCap(3), OpOverflow(OverflowDropNewest), OpElasticStats(&stats))
+	waitFor(t, sent)
+
+	res := Collect(out)
+	exp := []int{0, 1, 2}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if stats.Dropped() != 7 {
+		t.Logf("expected, %v, but got %v", 7, stats.Dropped())
+		t.Fail()
+	}
+}
+
+func TestElasticDropOldest(t *testing.T) {
+	in, sent := produce(10)
+	out, _ := Elastic(in, OpElasticCap(3), OpOverflow(OverflowDropOldest))
+	waitFor(t, sent)
+
+	res := Collect(out)
+	exp := []int{7, 8, 9}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestElasticBlock(t *testing.T) {
+	in, sent := produce(10)
+	var stats ElasticStats
+	out, _ := Elastic(in, OpElasticCap(3), OpElasticStats(&stats))
+
+	select {
+	case <-sent:
+		t.Log("expected the producer to be blocked")
+		t.Fail()
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	res := Collect(out)
+	exp := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if stats.HighWater() != 3 {
+		t.Logf("expected, %v, but got %v", 3, stats.HighWater())
+		t.Fail()
+	}
+}
+
+func TestElasticError(t *testing.T) {
+	in, _ := produce(10)
+	p := NewPipeline(context.Background())
+	var stats ElasticStats
+	out, _ := Elastic(in, p.Options(OpElasticCap(3), OpOverflow(OverflowError), OpElasticStats(&stats), OpDrain())...)
+
+	time.Sleep(20 * time.Millisecond)
+	DropAll(out, false)
+
+	if !errors.Is(p.Wait(), ErrOverflow) || !errors.Is(stats.Err(), ErrOverflow) {
+		t.Logf("expected, %v, but got %v and %v", ErrOverflow, p.Wait(), stats.Err())
+		t.Fail()
+	}
+}
+
+func TestElasticErr(t *testing.T) {
+	in, _ := produce(10)
+	out, errFn := Elastic(in, OpElasticCap(3), OpOverflow(OverflowError))
+	time.Sleep(20 * time.Millisecond)
+	DropAll(out, false)
+	if err := errFn(); err != ErrOverflow {
+		t.Logf("expected, %v, but got %v", ErrOverflow, err)
+		t.Fail()
+	}
+
+	in, _ = produce(10)
+	out, errFn = Elastic(in, OpElasticCap(3), OpOverflow(OverflowDisconnect))
+	time.Sleep(20 * time.Millisecond)
+	DropAll(out, false)
+	if err := errFn(); err != nil {
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+}
+
+func TestElasticUnknownPolicy(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Log("expected a panic for an unknown overflow policy")
+			t.Fail()
+		}
+	}()
+	Elastic(make(chan int), OpOverflow(OverflowPolicy(42)))
+}
+
+func TestRingShrinks(t *testing.T) {
+	r := &ring[int]{min: 32}
+	for i := 0; i < 1000; i++ {
+		r.push(i)
+	}
+	for i := 0; i < 1000; i++ {
+		if e := r.pop(); e != i {
+			t.Logf("expected, %v, but got %v", i, e)
+			t.FailNow()
+		}
+	}
+	if len(r.buf) != 32 {
+		t.Logf("expected, %v, but got %v", 32, len(r.buf))
+		t.Fail()
+	}
+}
@@ -0,0 +1,213 @@
+package chanz
+
+import (
+	"errors"
+	"github.com/modfin/henry/mon"
+	"github.com/modfin/henry/slicez"
+	"strings"
+)
+
+// Errors holds every error reported by an error aware stage, in the order they were received
+type Errors []error
+
+func (e Errors) Error() string {
+	return strings.Join(slicez.Map(e, func(err error) string {
+		return err.Error()
+	}), "\n")
+}
+
+// Unwrap returns the joined errors
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Is reports if any of the errors matches target, which lets errors.Is inspect each one of them
+func (e Errors) Is(target error) bool {
+	return slicez.SomeBy(e, func(err error) bool {
+		return errors.Is(err, target)
+	})
+}
+
+// As finds the first of the errors that matches target, and if so sets target to it and returns true, which lets
+// errors.As inspect each one of them
+func (e Errors) As(target any) bool {
+	return slicez.SomeBy(e, func(err error) bool {
+		return errors.As(err, target)
+	})
+}
+
+// joinErrors returns nil if there are no errors, the error itself if there is one, and Errors otherwise
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return Errors(errs)
+}
+
+// MapErr will take a chan, in, and executes mapper and put the result, wrapped in a mon.Result, on to the return chan.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpFailFast is supplied, it will stop after emitting the first error
+func MapErr[A any, B any](in <-chan A, mapper func(a A) (B, error), options ...Option) <-chan mon.Result[B] {
+	return FlatMapErr(in, func(a A) ([]B, error) {
+		b, err := mapper(a)
+		if err != nil {
+			return nil, err
+		}
+		return []B{b}, nil
+	}, options...)
+}
+
+// MapErrWith will take a chan, in, and executes mapper and put the result, wrapped in a mon.Result, on to the return chan.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpFailFast is supplied, it will stop after emitting the first error
+func MapErrWith[A any, B any](options ...Option) func(in <-chan A, mapper func(a A) (B, error)) <-chan mon.Result[B] {
+	return func(in <-chan A, mapper func(a A) (B, error)) <-chan mon.Result[B] {
+		return MapErr(in, mapper, options...)
+	}
+}
+
+// FilterErr takes a chan and applies the "include" func to every item. If it returns true, the item is put on the
+// output chan wrapped in a mon.Result. If it returns an error, the error is put on the output chan instead.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpFailFast is supplied, it will stop after emitting the first error
+func FilterErr[A any](in <-chan A, include func(a A) (bool, error), options ...Option) <-chan mon.Result[A] {
+	return FlatMapErr(in, func(a A) ([]A, error) {
+		ok, err := include(a)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []A{a}, nil
+	}, options...)
+}
+
+// FilterErrWith takes a chan and applies the "include" func to every item. If it returns true, the item is put on the
+// output chan wrapped in a mon.Result. If it returns an error, the error is put on the output chan instead.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpFailFast is supplied, it will stop after emitting the first error
+func FilterErrWith[A any](options ...Option) func(in <-chan A, include func(a A) (bool, error)) <-chan mon.Result[A] {
+	return func(in <-chan A, include func(a A) (bool, error)) <-chan mon.Result[A] {
+		return FilterErr(in, include, options...)
+	}
+}
+
+// FlatMapErr will take a chan, in, and executes mapper and put every item of the resulting slice, wrapped in a
+// mon.Result, on to the return chan. If mapper returns an error, the error is put on the return chan instead.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpFailFast is supplied, it will stop after emitting the first error, and drain "in", as if OpDrain was supplied,
+// so that stages upstream are not left blocked
+func FlatMapErr[A any, B any](in <-chan A, mapper func(a A) ([]B, error), options ...Option) <-chan mon.Result[B] {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	out := make(chan mon.Result[B], s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			var bs []B
+			var err error
+			switch call(s, func() { bs, err = mapper(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if err != nil {
+				if s.fail != nil {
+					s.fail(err)
+				}
+				if !send(s, out, mon.Err[B](err)) {
+					return
+				}
+				if s.failFast {
+					if s.cancel != nil {
+						s.cancel()
+					}
+					s.stop.stop()
+					return
+				}
+				continue
+			}
+			for _, b := range bs {
+				if !send(s, out, mon.Ok(b)) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FlatMapErrWith will take a chan, in, and executes mapper and put every item of the resulting slice, wrapped in a
+// mon.Result, on to the return chan. If mapper returns an error, the error is put on the return chan instead.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+// If OpFailFast is supplied, it will stop after emitting the first error, and drain "in", as if OpDrain was supplied,
+// so that stages upstream are not left blocked
+func FlatMapErrWith[A any, B any](options ...Option) func(in <-chan A, mapper func(a A) ([]B, error)) <-chan mon.Result[B] {
+	return func(in <-chan A, mapper func(a A) ([]B, error)) <-chan mon.Result[B] {
+		return FlatMapErr(in, mapper, options...)
+	}
+}
+
+// CollectResults will collect all results in a channel. The values of all ok results are returned in a slice and the
+// errors of all failed results are joined into one error.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the values so far are returned and the context error, or ErrDone, is joined with
+// the errors.
+// If OpFailFast is supplied, it will stop, and call cancel, at the first error. The rest of "c" is then read and
+// discarded in the background, so that stages upstream are not left blocked.
+func CollectResults[B any](c <-chan mon.Result[B], options ...Option) ([]B, error) {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	var out []B
+	var errs []error
+	for {
+		r, more, err := receiveErr(s, c)
+		if err != nil {
+			if s.drain {
+				go drain(s, c)
+			}
+			return out, joinErrors(append(errs, err))
+		}
+		if !more {
+			return out, joinErrors(errs)
+		}
+		val, err := r.Get()
+		if err != nil {
+			errs = append(errs, err)
+			if s.failFast {
+				if s.cancel != nil {
+					s.cancel()
+				}
+				go func() {
+					for range c {
+					}
+				}()
+				return out, joinErrors(errs)
+			}
+		} else {
+			out = append(out, val)
+		}
+	}
+}
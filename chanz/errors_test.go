@@ -0,0 +1,155 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/modfin/henry/mon"
+	"github.com/modfin/henry/slicez"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMapErr(t *testing.T) {
+	c := MapErr(Generate("1", "2", "a", "4", "b"), strconv.Atoi)
+
+	res, err := CollectResults(c)
+	exp := []int{1, 2, 4}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Logf("expected 2 joined errors, but got %v", err)
+		t.Fail()
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) || numErr.Num != "a" {
+		t.Logf("expected to find the error of %q among the joined errors, but got %v", "a", numErr)
+		t.Fail()
+	}
+	if !errors.Is(err, strconv.ErrSyntax) || !errs.Is(strconv.ErrSyntax) {
+		t.Logf("expected, %v, to be among the joined errors, but got %v", strconv.ErrSyntax, err)
+		t.Fail()
+	}
+}
+
+func TestMapErrFailFast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case in <- i:
+			}
+		}
+	}()
+
+	c := MapErr(in, func(a int) (int, error) {
+		if a == 3 {
+			return 0, fmt.Errorf("bad value %d", a)
+		}
+		return a, nil
+	}, OpContext(ctx), OpFailFast(cancel))
+
+	res, err := CollectResults(c)
+	exp := []int{0, 1, 2}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if err == nil || err.Error() != "bad value 3" {
+		t.Logf("expected error, bad value 3, but got %v", err)
+		t.Fail()
+	}
+	if ctx.Err() == nil {
+		t.Log("expected context to be cancelled")
+		t.Fail()
+	}
+}
+
+func TestFilterErr(t *testing.T) {
+	c := FilterErr(Generate(1, 2, 3, 4, 5, 6), func(a int) (bool, error) {
+		if a == 5 {
+			return false, fmt.Errorf("five")
+		}
+		return a%2 == 0, nil
+	})
+
+	res, err := CollectResults(c)
+	exp := []int{2, 4, 6}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if err == nil || err.Error() != "five" {
+		t.Logf("expected error, five, but got %v", err)
+		t.Fail()
+	}
+}
+
+func TestFlatMapErr(t *testing.T) {
+	c := FlatMapErr(Generate(1, 2, 3), func(a int) ([]int, error) {
+		return []int{a, a * 10}, nil
+	})
+
+	res, err := CollectResults(c)
+	exp := []int{1, 10, 2, 20, 3, 30}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if err != nil {
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+}
+
+func TestMapErrFailFastDrains(t *testing.T) {
+	in, finished := unbuffered(100)
+	c := MapErr(in, func(a int) (int, error) {
+		if a == 3 {
+			return 0, fmt.Errorf("bad value %d", a)
+		}
+		return a, nil
+	}, OpFailFast(nil))
+
+	res, err := CollectResults(c)
+	exp := []int{1, 2}
+	if !slicez.Equal(res, exp) || err == nil {
+		t.Logf("expected, %v and an error, but got %v, %v", exp, res, err)
+		t.Fail()
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("expected the producer not to be left blocked")
+	}
+}
+
+func TestCollectResultsDone(t *testing.T) {
+	in := make(chan mon.Result[int], 1)
+	in <- mon.Ok(1)
+	done := make(chan struct{})
+	go func() {
+		for len(in) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+
+	res, err := CollectResults(in, OpDone(done))
+	exp := []int{1}
+	if !slicez.Equal(res, exp) || !errors.Is(err, ErrDone) {
+		t.Logf("expected, %v, %v, but got %v, %v", exp, ErrDone, res, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,270 @@
+package chanz
+
+import (
+	"context"
+	"sync"
+)
+
+// FlatMap will take a chan, in, and executes mapper and put every item of the resulting slice on to the return chan.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func FlatMap[A any, B any](in <-chan A, mapper func(a A) []B, options ...Option) <-chan B {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	out := make(chan B, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			var bs []B
+			switch call(s, func() { bs = mapper(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			for _, b := range bs {
+				if !send(s, out, b) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FlatMapWith will take a chan, in, and executes mapper and put every item of the resulting slice on to the return chan.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func FlatMapWith[A any, B any](options ...Option) func(in <-chan A, mapper func(a A) []B) <-chan B {
+	return func(in <-chan A, mapper func(a A) []B) <-chan B {
+		return FlatMap(in, mapper, options...)
+	}
+}
+
+// innerContext returns a context that is cancelled once the stage stops, and the func that stops it
+func innerContext(s settings) (context.Context, context.CancelFunc) {
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if s.done != nil {
+		go func() {
+			select {
+			case <-s.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// MergeMap will take a chan, in, and executes mapper for every item, which returns an inner chan. Up to maxInner
+// inner chans are read at once, and every item read from them is put on to the return chan, in the order they are read.
+// Once maxInner inner chans are open, no more items are read from "in" until one of them is closed. A maxInner of 0
+// means no limit.
+// The ctx passed to mapper is cancelled once the stage stops, and the inner chan should then be closed.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in" and every inner chan, "done" channel is closed or the context.Done is closed, which is
+// supplied in Option
+func MergeMap[A any, B any](in <-chan A, mapper func(ctx context.Context, a A) <-chan B, maxInner int, options ...Option) <-chan B {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+	ctx, cancel := innerContext(s)
+	s.done = ctx.Done()
+
+	out := make(chan B, s.buffer)
+	var wg sync.WaitGroup
+	var sem chan struct{}
+	if maxInner > 0 {
+		sem = make(chan struct{}, maxInner)
+	}
+
+	forward := func(inner <-chan B) {
+		defer wg.Done()
+		defer drain(s, inner)
+		defer func() {
+			if sem != nil {
+				<-sem
+			}
+		}()
+		for {
+			e, more := receive(s, inner)
+			if !more {
+				return
+			}
+			if !send(s, out, e) {
+				return
+			}
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer recoverStage(s)
+		defer drain(s, in)
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			if sem != nil {
+				select {
+				case <-s.done:
+					return
+				case sem <- struct{}{}:
+				}
+			}
+			var inner <-chan B
+			switch call(s, func() { inner = mapper(ctx, e) }) {
+			case callSkip:
+				if sem != nil {
+					<-sem
+				}
+				continue
+			case callStop:
+				cancel()
+				return
+			}
+			wg.Add(1)
+			go forward(inner)
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+		observeClosed(s)
+	}()
+	return out
+}
+
+// MergeMapWith will take a chan, in, and executes mapper for every item, which returns an inner chan. Up to maxInner
+// inner chans are read at once, and every item read from them is put on to the return chan, in the order they are read.
+// Once maxInner inner chans are open, no more items are read from "in" until one of them is closed. A maxInner of 0
+// means no limit.
+// The ctx passed to mapper is cancelled once the stage stops, and the inner chan should then be closed.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in" and every inner chan, "done" channel is closed or the context.Done is closed, which is
+// supplied in Option
+func MergeMapWith[A any, B any](options ...Option) func(in <-chan A, mapper func(ctx context.Context, a A) <-chan B, maxInner int) <-chan B {
+	return func(in <-chan A, mapper func(ctx context.Context, a A) <-chan B, maxInner int) <-chan B {
+		return MergeMap(in, mapper, maxInner, options...)
+	}
+}
+
+// SwitchMap will take a chan, in, and executes mapper for every item, which returns an inner chan. Items read from the
+// latest inner chan are put on to the return chan, and as soon as a new item is read from "in", the previous inner
+// chan is abandoned and its ctx is cancelled, and it is drained in the background until it is closed. This is useful
+// when a new item supersedes the old, such as a newer search query superseding an older one.
+// The ctx passed to mapper is cancelled once the inner chan is abandoned or the stage stops, and the inner chan should
+// then be closed.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in" and the latest inner chan, "done" channel is closed or the context.Done is closed, which is
+// supplied in Option
+func SwitchMap[A any, B any](in <-chan A, mapper func(ctx context.Context, a A) <-chan B, options ...Option) <-chan B {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+	ctx, cancel := innerContext(s)
+	s.done = ctx.Done()
+
+	out := make(chan B, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		defer cancel()
+
+		// current is the latest inner chan, which is forwarded until it is closed or abandoned. An item read from it is
+		// kept in pending until it is written, or dropped if a new item is read from "in" in the meantime.
+		var current <-chan B
+		var cancelCurrent context.CancelFunc = func() {}
+		defer func() {
+			cancelCurrent()
+		}()
+		var pending B
+		var hasPending bool
+
+		src := in
+		for src != nil || current != nil || hasPending {
+			reading := current
+			var writing chan<- B
+			if hasPending {
+				reading, writing = nil, out
+			}
+
+			select {
+			case <-s.done:
+				drain(s, current)
+				return
+			case e, ok := <-src:
+				if !ok {
+					src = nil
+					continue
+				}
+				observeReceived(s)
+
+				cancelCurrent()
+				if current != nil { // The abandoned inner chan is drained, so its producer is never left blocked
+					DropAll(current, true)
+				}
+				current, hasPending = nil, false
+				innerCtx, innerCancel := context.WithCancel(ctx)
+				cancelCurrent = innerCancel
+				var inner <-chan B
+				switch call(s, func() { inner = mapper(innerCtx, e) }) {
+				case callSkip:
+					continue
+				case callStop:
+					return
+				}
+				current = inner
+			case e, ok := <-reading:
+				if !ok {
+					current = nil
+					continue
+				}
+				pending, hasPending = e, true
+			case writing <- pending:
+				observeEmitted(s)
+				hasPending = false
+			}
+		}
+	}()
+	return out
+}
+
+// SwitchMapWith will take a chan, in, and executes mapper for every item, which returns an inner chan. Items read from
+// the latest inner chan are put on to the return chan, and as soon as a new item is read from "in", the previous inner
+// chan is abandoned and its ctx is cancelled, and it is drained in the background until it is closed.
+// The ctx passed to mapper is cancelled once the inner chan is abandoned or the stage stops, and the inner chan should
+// then be closed.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in" and the latest inner chan, "done" channel is closed or the context.Done is closed, which is
+// supplied in Option
+func SwitchMapWith[A any, B any](options ...Option) func(in <-chan A, mapper func(ctx context.Context, a A) <-chan B) <-chan B {
+	return func(in <-chan A, mapper func(ctx context.Context, a A) <-chan B) <-chan B {
+		return SwitchMap(in, mapper, options...)
+	}
+}
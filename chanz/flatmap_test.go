@@ -0,0 +1,213 @@
+package chanz
+
+import (
+	"context"
+	"github.com/modfin/henry/slicez"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// repeat returns a chan that writes a until ctx is cancelled, or n times if n > 0, and then closes
+func repeat[A any](ctx context.Context, a A, n int) <-chan A {
+	out := make(chan A)
+	go func() {
+		defer close(out)
+		for i := 0; n <= 0 || i < n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- a:
+			}
+		}
+	}()
+	return out
+}
+
+// tracked returns a mapper, for MergeMap and SwitchMap, of inner chans that repeat a until ctx is cancelled, and a
+// func that reports if every inner chan returned by the mapper so far has closed within a second
+func tracked[A any]() (func(ctx context.Context, a A) <-chan A, func() bool) {
+	var wg sync.WaitGroup
+	mapper := func(ctx context.Context, a A) <-chan A {
+		wg.Add(1)
+		out := make(chan A)
+		go func() {
+			defer wg.Done()
+			defer close(out)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- a:
+				}
+			}
+		}()
+		return out
+	}
+	closed := func() bool {
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+			return true
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	return mapper, closed
+}
+
+func TestFlatMap(t *testing.T) {
+	res := Collect(FlatMap(Generate(1, 2, 3), func(a int) []int {
+		return slicez.RepeatBy(a, func(int) int { return a })
+	}))
+	exp := []int{1, 2, 2, 3, 3, 3}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestMergeMap(t *testing.T) {
+	var running, maxRunning int32
+	res := Collect(MergeMap(Generate(1, 2, 3, 4, 5), func(ctx context.Context, a int) <-chan int {
+		out := make(chan int)
+		go func() {
+			defer close(out)
+			cur := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if cur <= m || atomic.CompareAndSwapInt32(&maxRunning, m, cur) {
+					break
+				}
+			}
+			for i := 0; i < 3; i++ {
+				time.Sleep(time.Millisecond)
+				out <- a
+			}
+		}()
+		return out
+	}, 2))
+
+	sort.Ints(res)
+	exp := []int{1, 1, 1, 2, 2, 2, 3, 3, 3, 4, 4, 4, 5, 5, 5}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	if maxRunning > 2 {
+		t.Logf("expected at most 2 inner chans at once, but got %d", maxRunning)
+		t.Fail()
+	}
+}
+
+func TestMergeMapDone(t *testing.T) {
+	mapper, closed := tracked[int]()
+	done := make(chan struct{})
+	out := MergeMap(Generate(1, 2, 3), mapper, 0, OpDone(done))
+	<-out
+	close(done)
+	DropAll(out, false)
+
+	if !closed() {
+		t.Log("expected every inner chan to be closed")
+		t.Fail()
+	}
+}
+
+func TestSwitchMap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan string)
+	cancelled := make(chan string, 2)
+	out := SwitchMap(in, func(ctx context.Context, q string) <-chan string {
+		go func() {
+			<-ctx.Done()
+			cancelled <- q
+		}()
+		return repeat(ctx, q, 0)
+	}, OpContext(ctx))
+
+	in <- "a"
+	if e := <-out; e != "a" {
+		t.Logf("expected, %v, but got %v", "a", e)
+		t.Fail()
+	}
+	in <- "b"
+	if q := <-cancelled; q != "a" {
+		t.Logf("expected, %v, but got %v", "a", q)
+		t.Fail()
+	}
+
+	for i := 0; i < 10; i++ {
+		if e := <-out; e != "b" {
+			t.Logf("expected, %v, but got %v", "b", e)
+			t.Fail()
+		}
+	}
+
+	close(in)
+	go DropAll(out, false)
+	select {
+	case q := <-cancelled:
+		t.Logf("expected b to keep going after in is closed, but %s was cancelled", q)
+		t.Fail()
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSwitchMapDrainsAbandoned(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	var finished <-chan struct{}
+	out := SwitchMap(in, func(ctx context.Context, a int) <-chan int {
+		if a == 1 { // Ignores ctx, and is only closed once every item is read
+			var c <-chan int
+			c, finished = unbuffered(100)
+			return c
+		}
+		return repeat(ctx, a, 0)
+	}, OpContext(ctx))
+
+	in <- 1
+	<-out
+	in <- 2
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Log("expected the abandoned inner chan to be drained")
+		t.Fail()
+	}
+}
+
+func TestSwitchMapCompletes(t *testing.T) {
+	res := Collect(SwitchMap(Generate("a"), func(ctx context.Context, q string) <-chan string {
+		return repeat(ctx, q, 3)
+	}))
+	exp := []string{"a", "a", "a"}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestSwitchMapDone(t *testing.T) {
+	mapper, closed := tracked[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := SwitchMap(Generate("a"), mapper, OpContext(ctx))
+	<-out
+	cancel()
+	DropAll(out, false)
+
+	if !closed() {
+		t.Log("expected every inner chan to be closed")
+		t.Fail()
+	}
+}
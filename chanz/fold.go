@@ -0,0 +1,172 @@
+package chanz
+
+import (
+	"errors"
+	"github.com/modfin/henry/compare"
+)
+
+// ErrEmpty is returned by terminals, such as Reduce, that has no result since their input chan is closed without any
+// items
+var ErrEmpty = errors.New("chanz: input was closed without any items")
+
+// Fold reads every item from c and combines it with the accumulator, starting with init, and returns the result once c
+// is closed. It reads one item at a time, so it runs in constant memory no matter how many items there are.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the result so far is returned along with the context error, or ErrDone.
+func Fold[A any, B any](c <-chan A, combined func(accumulator B, val A) B, init B, options ...Option) (B, error) {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	for {
+		e, more, err := receiveErr(s, c)
+		if err != nil {
+			if s.drain {
+				go drain(s, c)
+			}
+			return init, err
+		}
+		if !more {
+			return init, nil
+		}
+		init = combined(init, e)
+	}
+}
+
+// Reduce reads every item from c and combines it with the accumulator, which starts out as the first item, and returns
+// the result once c is closed. If c is closed without any items, the zero value is returned along with ErrEmpty.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the result so far is returned along with the context error, or ErrDone.
+func Reduce[A any](c <-chan A, combined func(accumulator A, val A) A, options ...Option) (A, error) {
+	type acc struct {
+		val A
+		ok  bool
+	}
+	res, err := Fold(c, func(a acc, val A) acc {
+		if !a.ok {
+			return acc{val: val, ok: true}
+		}
+		return acc{val: combined(a.val, val), ok: true}
+	}, acc{}, options...)
+	if err == nil && !res.ok {
+		err = ErrEmpty
+	}
+	return res.val, err
+}
+
+// Count reads every item from c and returns the number of items once c is closed.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the count so far is returned along with the context error, or ErrDone.
+func Count[A any](c <-chan A, options ...Option) (int, error) {
+	return Fold(c, func(n int, _ A) int {
+		return n + 1
+	}, 0, options...)
+}
+
+// MinBy reads every item from c and returns the smallest one, according to less, once c is closed. If several items
+// are the smallest, the first one is returned. If c is closed without any items, the zero value is returned along
+// with ErrEmpty.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the smallest item so far is returned along with the context error, or ErrDone.
+func MinBy[A any](c <-chan A, less func(a, b A) bool, options ...Option) (A, error) {
+	return Reduce(c, func(min A, val A) A {
+		if less(val, min) {
+			return val
+		}
+		return min
+	}, options...)
+}
+
+// MaxBy reads every item from c and returns the largest one, according to less, once c is closed. If several items
+// are the largest, the first one is returned. If c is closed without any items, the zero value is returned along
+// with ErrEmpty.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the largest item so far is returned along with the context error, or ErrDone.
+func MaxBy[A any](c <-chan A, less func(a, b A) bool, options ...Option) (A, error) {
+	return Reduce(c, func(max A, val A) A {
+		if less(max, val) {
+			return val
+		}
+		return max
+	}, options...)
+}
+
+// SumOf reads every item from c and returns the sum of the numbers picked from them by fn once c is closed.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the sum so far is returned along with the context error, or ErrDone.
+func SumOf[A any, N compare.Number](c <-chan A, fn func(a A) N, options ...Option) (N, error) {
+	var zero N
+	return Fold(c, func(sum N, val A) N {
+		return sum + fn(val)
+	}, zero, options...)
+}
+
+// MeanOf reads every item from c and returns the mean of the numbers picked from them by fn once c is closed. If c is
+// closed without any items, 0 is returned along with ErrEmpty.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option. If it is
+// stopped by "done" or the context, the mean so far is returned along with the context error, or ErrDone.
+func MeanOf[A any, N compare.Number](c <-chan A, fn func(a A) N, options ...Option) (float64, error) {
+	type acc struct {
+		mean float64
+		n    int
+	}
+	res, err := Fold(c, func(a acc, val A) acc {
+		a.n++
+		a.mean += (float64(fn(val)) - a.mean) / float64(a.n) // A running mean does not overflow, unlike a running sum
+		return a
+	}, acc{}, options...)
+	if err == nil && res.n == 0 {
+		err = ErrEmpty
+	}
+	return res.mean, err
+}
+
+// Scan reads every item from c, combines it with the accumulator, starting with init, and writes the new accumulator
+// onto the return chan. It is the streaming equivalent of Fold, emitting every intermediate result.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Scan[A any, B any](c <-chan A, combined func(accumulator B, val A) B, init B, options ...Option) <-chan B {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	out := make(chan B, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, c)
+		defer observeClosed(s)
+		defer close(out)
+		acc := init
+		for {
+			e, more := receive(s, c)
+			if !more {
+				return
+			}
+			var next B
+			switch call(s, func() { next = combined(acc, e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			acc = next
+			if !send(s, out, acc) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ScanWith reads every item from c, combines it with the accumulator, starting with init, and writes the new
+// accumulator onto the return chan. It is the streaming equivalent of Fold, emitting every intermediate result.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func ScanWith[A any, B any](options ...Option) func(c <-chan A, combined func(accumulator B, val A) B, init B) <-chan B {
+	return func(c <-chan A, combined func(accumulator B, val A) B, init B) <-chan B {
+		return Scan(c, combined, init, options...)
+	}
+}
@@ -0,0 +1,118 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	res, err := Fold(Generate(1, 2, 3, 4), func(acc string, a int) string {
+		return acc + string(rune('0'+a))
+	}, ">")
+	if err != nil || res != ">1234" {
+		t.Logf("expected, %v, but got %v, %v", ">1234", res, err)
+		t.Fail()
+	}
+}
+
+func TestFoldCancelled(t *testing.T) {
+	in := make(chan int)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		in <- 1
+		in <- 2
+		cancel()
+	}()
+
+	res, err := Fold(in, func(acc int, a int) int { return acc + a }, 0, OpContext(ctx))
+	if res != 3 || !errors.Is(err, context.Canceled) {
+		t.Logf("expected, %v, %v, but got %v, %v", 3, context.Canceled, res, err)
+		t.Fail()
+	}
+}
+
+func TestFoldDone(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	_, err := Fold(make(chan int), func(acc int, a int) int { return acc + a }, 0, OpDone(done))
+	if err != ErrDone {
+		t.Logf("expected, %v, but got %v", ErrDone, err)
+		t.Fail()
+	}
+}
+
+func TestReduce(t *testing.T) {
+	res, err := Reduce(Generate(3, 4, 5), func(acc int, a int) int { return acc * a })
+	if err != nil || res != 60 {
+		t.Logf("expected, %v, but got %v, %v", 60, res, err)
+		t.Fail()
+	}
+	res, err = Reduce(Generate[int](), func(acc int, a int) int { return acc * a })
+	if err != ErrEmpty || res != 0 {
+		t.Logf("expected, %v, %v, but got %v, %v", 0, ErrEmpty, res, err)
+		t.Fail()
+	}
+}
+
+func TestCount(t *testing.T) {
+	res, err := Count(Generate("a", "b", "c"))
+	if err != nil || res != 3 {
+		t.Logf("expected, %v, but got %v, %v", 3, res, err)
+		t.Fail()
+	}
+}
+
+func TestMinMaxBy(t *testing.T) {
+	type item struct {
+		key, val int
+	}
+	less := func(a, b item) bool { return a.key < b.key }
+	items := []item{{3, 0}, {1, 1}, {5, 2}, {1, 3}, {5, 4}}
+
+	min, err := MinBy(Generate(items...), less)
+	if err != nil || min != (item{1, 1}) {
+		t.Logf("expected, %v, but got %v, %v", item{1, 1}, min, err)
+		t.Fail()
+	}
+	max, err := MaxBy(Generate(items...), less)
+	if err != nil || max != (item{5, 2}) {
+		t.Logf("expected, %v, but got %v, %v", item{5, 2}, max, err)
+		t.Fail()
+	}
+	if _, err := MinBy(Generate[item](), less); err != ErrEmpty {
+		t.Logf("expected, %v, but got %v", ErrEmpty, err)
+		t.Fail()
+	}
+}
+
+func TestSumMeanOf(t *testing.T) {
+	words := []string{"a", "bb", "cccc", "d"}
+	length := func(s string) int { return len(s) }
+
+	sum, err := SumOf(Generate(words...), length)
+	if err != nil || sum != 8 {
+		t.Logf("expected, %v, but got %v, %v", 8, sum, err)
+		t.Fail()
+	}
+	mean, err := MeanOf(Generate(words...), length)
+	if err != nil || mean != 2 {
+		t.Logf("expected, %v, but got %v, %v", 2, mean, err)
+		t.Fail()
+	}
+	mean, err = MeanOf(Generate[string](), length)
+	if err != ErrEmpty || mean != 0 {
+		t.Logf("expected, %v, %v, but got %v, %v", 0, ErrEmpty, mean, err)
+		t.Fail()
+	}
+}
+
+func TestScan(t *testing.T) {
+	res := Collect(Scan(Generate(1, 2, 3, 4), func(acc int, a int) int { return acc + a }, 10))
+	exp := []int{11, 13, 16, 20}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
@@ -0,0 +1,241 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/mon"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrNoFutures is the error of the Future returned by Any or Race when they are given no futures
+var ErrNoFutures = errors.New("chanz: no futures")
+
+// Future is the result of work that is done asynchronously, such as a task submitted to a Pool or started by Async. It
+// can be copied and awaited any number of times.
+type Future[T any] struct {
+	done <-chan struct{}
+	res  *mon.Result[T]
+}
+
+// Done returns a chan that is closed once the result of the Future is available
+func (f Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result of the Future. If ctx is cancelled before the result is available, the error of ctx is
+// returned instead.
+func (f Future[T]) Await(ctx context.Context) mon.Result[T] {
+	select {
+	case <-f.done:
+		return *f.res
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return mon.Err[T](ctx.Err())
+	case <-f.done:
+		return *f.res
+	}
+}
+
+// promise is the writing end of a Future, only the first result it is completed with is kept
+type promise[T any] struct {
+	done chan struct{}
+	res  *mon.Result[T]
+	once *sync.Once
+}
+
+func newPromise[T any]() promise[T] {
+	return promise[T]{
+		done: make(chan struct{}),
+		res:  new(mon.Result[T]),
+		once: new(sync.Once),
+	}
+}
+
+func (p promise[T]) complete(res mon.Result[T]) {
+	p.once.Do(func() {
+		*p.res = res
+		close(p.done)
+	})
+}
+
+func (p promise[T]) future() Future[T] {
+	return Future[T]{done: p.done, res: p.res}
+}
+
+// try calls fn and returns its result, a panic in fn is recovered and returned as a Panic error
+func try[T any](fn func() (T, error)) (res mon.Result[T]) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = mon.Err[T](Panic{Value: r, Stack: debug.Stack()})
+		}
+	}()
+	return mon.TupleToResult(fn())
+}
+
+// Async calls fn in a new goroutine and returns a Future of its result. A panic in fn is recovered and the Future
+// fails with a Panic error.
+// If ctx is cancelled before fn returns, the Future fails with the error of ctx right away, and the result of fn is
+// discarded once it returns. fn is not called at all if ctx is already cancelled. To stop the work itself, fn should
+// close over ctx, e.g.
+//
+//	f := Async(ctx, func() (*http.Response, error) {
+//		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//		return http.DefaultClient.Do(req)
+//	})
+func Async[T any](ctx context.Context, fn func() (T, error)) Future[T] {
+	p := newPromise[T]()
+	if err := ctx.Err(); err != nil {
+		p.complete(mon.Err[T](err))
+		return p.future()
+	}
+	go func() {
+		p.complete(try(fn))
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.complete(mon.Err[T](ctx.Err()))
+		case <-p.done:
+		}
+	}()
+	return p.future()
+}
+
+// settle calls fn with the index and result of each future in fs, in the order they are done, until fn returns false
+// or every future is done. If ctx is cancelled first, the error of ctx is returned.
+func settle[T any](ctx context.Context, fs []Future[T], fn func(i int, res mon.Result[T]) bool) error {
+	quit := make(chan struct{})
+	defer close(quit)
+
+	done := make(chan int, len(fs))
+	for i, f := range fs {
+		go func(i int, f Future[T]) {
+			select {
+			case <-f.done:
+				done <- i
+			case <-quit:
+			}
+		}(i, f)
+	}
+
+	for range fs {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case i := <-done:
+			if !fn(i, *fs[i].res) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// All returns a Future of the values of every future in fs, in the same order as fs. It fails with the error of the
+// first future in fs to fail, without waiting for the rest, or with the error of ctx if it is cancelled first.
+func All[T any](ctx context.Context, fs ...Future[T]) Future[[]T] {
+	p := newPromise[[]T]()
+	go func() {
+		values := make([]T, len(fs))
+		err := settle(ctx, fs, func(i int, res mon.Result[T]) bool {
+			if !res.Ok() {
+				p.complete(mon.Err[[]T](res.Error()))
+				return false
+			}
+			values[i] = res.OrEmpty()
+			return true
+		})
+		if err != nil {
+			p.complete(mon.Err[[]T](err))
+		}
+		p.complete(mon.Ok(values))
+	}()
+	return p.future()
+}
+
+// AllSettled returns a Future of the results of every future in fs, in the same order as fs, once all of them are
+// done, successfully or not. It only fails if ctx is cancelled first, with the error of ctx.
+func AllSettled[T any](ctx context.Context, fs ...Future[T]) Future[[]mon.Result[T]] {
+	p := newPromise[[]mon.Result[T]]()
+	go func() {
+		results := make([]mon.Result[T], len(fs))
+		err := settle(ctx, fs, func(i int, res mon.Result[T]) bool {
+			results[i] = res
+			return true
+		})
+		if err != nil {
+			p.complete(mon.Err[[]mon.Result[T]](err))
+		}
+		p.complete(mon.Ok(results))
+	}()
+	return p.future()
+}
+
+// Any returns a Future of the value of the first future in fs to succeed. If every future fails, it fails with
+// Errors holding their errors, in the same order as fs, or with the error itself if there is only one. It fails with
+// the error of ctx if it is cancelled first, and with ErrNoFutures if fs is empty.
+func Any[T any](ctx context.Context, fs ...Future[T]) Future[T] {
+	p := newPromise[T]()
+	if len(fs) == 0 {
+		p.complete(mon.Err[T](ErrNoFutures))
+		return p.future()
+	}
+	go func() {
+		errs := make([]error, len(fs))
+		err := settle(ctx, fs, func(i int, res mon.Result[T]) bool {
+			if res.Ok() {
+				p.complete(res)
+				return false
+			}
+			errs[i] = res.Error()
+			return true
+		})
+		if err != nil {
+			p.complete(mon.Err[T](err))
+		}
+		p.complete(mon.Err[T](joinErrors(errs)))
+	}()
+	return p.future()
+}
+
+// Race returns a Future of the result of the first future in fs to be done, successfully or not. It fails with the
+// error of ctx if it is cancelled first, and with ErrNoFutures if fs is empty.
+func Race[T any](ctx context.Context, fs ...Future[T]) Future[T] {
+	p := newPromise[T]()
+	if len(fs) == 0 {
+		p.complete(mon.Err[T](ErrNoFutures))
+		return p.future()
+	}
+	go func() {
+		err := settle(ctx, fs, func(i int, res mon.Result[T]) bool {
+			p.complete(res)
+			return false
+		})
+		if err != nil {
+			p.complete(mon.Err[T](err))
+		}
+	}()
+	return p.future()
+}
+
+// Then returns a Future of the result of calling fn with the value of f, once f is done. If f fails, fn is not called
+// and the returned Future fails with the same error. A panic in fn is recovered and the Future fails with a Panic
+// error.
+func Then[A any, B any](f Future[A], fn func(a A) (B, error)) Future[B] {
+	p := newPromise[B]()
+	go func() {
+		<-f.done
+		a, err := f.res.Get()
+		if err != nil {
+			p.complete(mon.Err[B](err))
+			return
+		}
+		p.complete(try(func() (B, error) {
+			return fn(a)
+		}))
+	}()
+	return p.future()
+}
@@ -0,0 +1,192 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/mon"
+	"github.com/modfin/henry/slicez"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// resolved returns a Future that is done with res after d
+func resolved[T any](res mon.Result[T], d time.Duration) Future[T] {
+	return Async(context.Background(), func() (T, error) {
+		time.Sleep(d)
+		return res.Get()
+	})
+}
+
+func TestAsync(t *testing.T) {
+	f := Async(context.Background(), func() (int, error) { return 42, nil })
+	if v, err := f.Await(context.Background()).Get(); err != nil || v != 42 {
+		t.Logf("expected, %v, but got %v, %v", 42, v, err)
+		t.Fail()
+	}
+
+	var pnc Panic
+	f = Async(context.Background(), func() (int, error) { panic("boom") })
+	if err := f.Await(context.Background()).Error(); !errors.As(err, &pnc) || pnc.Value != "boom" {
+		t.Logf("expected a recovered panic, but got %v", err)
+		t.Fail()
+	}
+}
+
+func TestAsyncCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	f := Async(ctx, func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	cancel()
+	if err := f.Await(context.Background()).Error(); err != context.Canceled {
+		t.Logf("expected, %v, but got %v", context.Canceled, err)
+		t.Fail()
+	}
+
+	var called bool
+	f = Async(ctx, func() (int, error) {
+		called = true
+		return 1, nil
+	})
+	if err := f.Await(context.Background()).Error(); err != context.Canceled || called {
+		t.Logf("expected, %v and fn not called, but got %v, called %v", context.Canceled, err, called)
+		t.Fail()
+	}
+}
+
+func TestAwaitCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	f := Async(context.Background(), func() (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.Await(ctx).Error(); err != context.Canceled {
+		t.Logf("expected, %v, but got %v", context.Canceled, err)
+		t.Fail()
+	}
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+	exp := []int{1, 2, 3}
+	res, err := All(ctx,
+		resolved(mon.Ok(1), 3*time.Millisecond),
+		resolved(mon.Ok(2), time.Millisecond),
+		resolved(mon.Ok(3), 2*time.Millisecond),
+	).Await(ctx).Get()
+	if err != nil || !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v, %v", exp, res, err)
+		t.Fail()
+	}
+
+	fail := errors.New("fail")
+	release := make(chan struct{})
+	defer close(release)
+	pending := Async(ctx, func() (int, error) {
+		<-release
+		return 0, nil
+	})
+	if err := All(ctx, pending, resolved(mon.Err[int](fail), 0)).Await(ctx).Error(); err != fail {
+		t.Logf("expected, %v, but got %v", fail, err)
+		t.Fail()
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	f := All(cctx, pending)
+	cancel()
+	if err := f.Await(ctx).Error(); err != context.Canceled {
+		t.Logf("expected, %v, but got %v", context.Canceled, err)
+		t.Fail()
+	}
+}
+
+func TestAllSettled(t *testing.T) {
+	ctx := context.Background()
+	fail := errors.New("fail")
+	res, err := AllSettled(ctx,
+		resolved(mon.Ok(1), 2*time.Millisecond),
+		resolved(mon.Err[int](fail), time.Millisecond),
+	).Await(ctx).Get()
+	if err != nil || len(res) != 2 || res[0].OrEmpty() != 1 || res[1].Error() != fail {
+		t.Logf("expected, [1 %v], but got %v, %v", fail, res, err)
+		t.Fail()
+	}
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+	fail := errors.New("fail")
+	v, err := Any(ctx,
+		resolved(mon.Err[int](fail), 0),
+		resolved(mon.Ok(2), 2*time.Millisecond),
+		resolved(mon.Ok(3), 20*time.Millisecond),
+	).Await(ctx).Get()
+	if err != nil || v != 2 {
+		t.Logf("expected, %v, but got %v, %v", 2, v, err)
+		t.Fail()
+	}
+
+	errs := []error{errors.New("a"), errors.New("b")}
+	err = Any(ctx,
+		resolved(mon.Err[int](errs[0]), 2*time.Millisecond),
+		resolved(mon.Err[int](errs[1]), 0),
+	).Await(ctx).Error()
+	var res Errors
+	if !errors.As(err, &res) || !slicez.EqualBy(res, errs, func(a, b error) bool { return a == b }) {
+		t.Logf("expected, %v, but got %v", errs, err)
+		t.Fail()
+	}
+
+	if err := Any[int](ctx).Await(ctx).Error(); err != ErrNoFutures {
+		t.Logf("expected, %v, but got %v", ErrNoFutures, err)
+		t.Fail()
+	}
+}
+
+func TestRace(t *testing.T) {
+	ctx := context.Background()
+	fail := errors.New("fail")
+	err := Race(ctx,
+		resolved(mon.Ok(1), 20*time.Millisecond),
+		resolved(mon.Err[int](fail), 0),
+	).Await(ctx).Error()
+	if err != fail {
+		t.Logf("expected, %v, but got %v", fail, err)
+		t.Fail()
+	}
+
+	if err := Race[int](ctx).Await(ctx).Error(); err != ErrNoFutures {
+		t.Logf("expected, %v, but got %v", ErrNoFutures, err)
+		t.Fail()
+	}
+}
+
+func TestThen(t *testing.T) {
+	ctx := context.Background()
+	f := Then(resolved(mon.Ok(21), 0), func(a int) (string, error) {
+		return strconv.Itoa(a * 2), nil
+	})
+	if v, err := f.Await(ctx).Get(); err != nil || v != "42" {
+		t.Logf("expected, %v, but got %v, %v", "42", v, err)
+		t.Fail()
+	}
+
+	fail := errors.New("fail")
+	var called bool
+	f = Then(resolved(mon.Err[int](fail), 0), func(a int) (string, error) {
+		called = true
+		return "", nil
+	})
+	if err := f.Await(ctx).Error(); err != fail || called {
+		t.Logf("expected, %v and fn not called, but got %v, called %v", fail, err, called)
+		t.Fail()
+	}
+}
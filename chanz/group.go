@@ -0,0 +1,161 @@
+package chanz
+
+import "time"
+
+// Group is a sub-stream created by GroupBy, holding the items with the same key
+type Group[K comparable, A any] struct {
+	Key   K
+	Items <-chan A
+}
+
+// OpGroupIdle makes GroupBy close a group once no item has been written to it for d. If an item with the same key
+// is read later on, a new group is created for it. Default is 0, which means groups are never closed for being idle.
+func OpGroupIdle(d time.Duration) Option {
+	return func(s settings) settings {
+		s.groupIdle = d
+		return s
+	}
+}
+
+// OpMaxGroups caps the number of groups GroupBy keeps open at once. When an item with a new key is read and there
+// already are n open groups, the group that least recently got an item is closed to make room. Default is 0, which
+// means no cap.
+func OpMaxGroups(n int) Option {
+	return func(s settings) settings {
+		s.maxGroups = n
+		return s
+	}
+}
+
+type group[A any] struct {
+	c    chan A
+	seq  uint64
+	last time.Time
+}
+
+// GroupBy splits "in" into one Group for every key, where key is the func that picks the key of an item. A Group is
+// written onto the return chan the first time an item with its key is read, and every item with that key is then
+// written onto the Items chan of the group. Every Group must be read, otherwise GroupBy is blocked.
+// Groups can be closed before "in" is, by OpGroupIdle and OpMaxGroups.
+// The return chan, and the chans of every Group, has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func GroupBy[A any, K comparable](in <-chan A, key func(a A) K, options ...Option) <-chan Group[K, A] {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	clock := clockOf(s)
+	out := make(chan Group[K, A], s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+
+		groups := map[K]*group[A]{}
+		defer func() {
+			for _, g := range groups {
+				close(g.c)
+			}
+		}()
+		closeGroup := func(k K) {
+			close(groups[k].c)
+			delete(groups, k)
+		}
+
+		var timer ClockTimer
+		var timeout <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		// arm makes the timer fire once the group that has been idle the longest, as of now, is due to be closed
+		arm := func(now time.Time) {
+			if s.groupIdle <= 0 || timer != nil || len(groups) == 0 {
+				return
+			}
+			var oldest time.Time
+			for _, g := range groups {
+				if oldest.IsZero() || g.last.Before(oldest) {
+					oldest = g.last
+				}
+			}
+			timer = clock.NewTimer(oldest.Add(s.groupIdle).Sub(now))
+			timeout = timer.C()
+		}
+
+		var seq uint64
+		for {
+			select {
+			case <-s.done:
+				return
+			case now := <-timeout:
+				timer, timeout = nil, nil
+				for k, g := range groups {
+					if !g.last.Add(s.groupIdle).After(now) {
+						closeGroup(k)
+					}
+				}
+				arm(now)
+			case e, ok := <-in:
+				if !ok {
+					return
+				}
+				observeReceived(s)
+
+				var k K
+				switch call(s, func() { k = key(e) }) {
+				case callSkip:
+					continue
+				case callStop:
+					return
+				}
+
+				g, found := groups[k]
+				if !found {
+					if s.maxGroups > 0 && len(groups) >= s.maxGroups {
+						var lru K
+						var min *group[A]
+						for k, g := range groups {
+							if min == nil || g.seq < min.seq {
+								lru, min = k, g
+							}
+						}
+						closeGroup(lru)
+					}
+					g = &group[A]{c: make(chan A, s.buffer)}
+					groups[k] = g
+					if !send(s, out, Group[K, A]{Key: k, Items: g.c}) {
+						return
+					}
+				}
+
+				seq++
+				g.seq = seq
+				if s.groupIdle > 0 {
+					g.last = clock.Now()
+					arm(g.last)
+				}
+				if !send(s, g.c, e) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// GroupByWith splits "in" into one Group for every key, where key is the func that picks the key of an item. A Group
+// is written onto the return chan the first time an item with its key is read, and every item with that key is then
+// written onto the Items chan of the group. Every Group must be read, otherwise GroupBy is blocked.
+// Groups can be closed before "in" is, by OpGroupIdle and OpMaxGroups.
+// The return chan, and the chans of every Group, has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func GroupByWith[A any, K comparable](options ...Option) func(in <-chan A, key func(a A) K) <-chan Group[K, A] {
+	return func(in <-chan A, key func(a A) K) <-chan Group[K, A] {
+		return GroupBy(in, key, options...)
+	}
+}
@@ -0,0 +1,131 @@
+package chanz
+
+import (
+	"context"
+	"github.com/modfin/henry/slicez"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectGroups reads every group, and the items of each one concurrently, and returns them in the order the groups
+// was read
+func collectGroups[K comparable, A any](groups <-chan Group[K, A]) ([]K, [][]A) {
+	var keys []K
+	var items []*[]A
+	var wg sync.WaitGroup
+	for g := range groups {
+		keys = append(keys, g.Key)
+		res := new([]A)
+		items = append(items, res)
+		wg.Add(1)
+		go func(g Group[K, A]) {
+			defer wg.Done()
+			*res = Collect(g.Items)
+		}(g)
+	}
+	wg.Wait()
+	return keys, slicez.Map(items, func(a *[]A) []A { return *a })
+}
+
+func TestGroupBy(t *testing.T) {
+	keys, items := collectGroups(GroupBy(Generate(1, 2, 3, 4, 5, 6, 7), func(a int) int { return a % 3 }))
+
+	expKeys := []int{1, 2, 0}
+	if !slicez.Equal(keys, expKeys) {
+		t.Logf("expected, %v, but got %v", expKeys, keys)
+		t.Fail()
+	}
+	exp := [][]int{{1, 4, 7}, {2, 5}, {3, 6}}
+	if !slicez.EqualBy(items, exp, slicez.Equal[int]) {
+		t.Logf("expected, %v, but got %v", exp, items)
+		t.Fail()
+	}
+}
+
+func TestGroupByMaxGroups(t *testing.T) {
+	in := Generate("a1", "b1", "a2", "c1", "b2")
+	keys, items := collectGroups(GroupBy(in, func(a string) byte { return a[0] }, OpMaxGroups(2)))
+
+	// c1 closes b, since a got an item more recently, and b2 then closes a
+	expKeys := []byte("abcb")
+	if !slicez.Equal(keys, expKeys) {
+		t.Logf("expected, %s, but got %s", expKeys, keys)
+		t.Fail()
+	}
+	exp := [][]string{{"a1", "a2"}, {"b1"}, {"c1"}, {"b2"}}
+	if !slicez.EqualBy(items, exp, slicez.Equal[string]) {
+		t.Logf("expected, %v, but got %v", exp, items)
+		t.Fail()
+	}
+}
+
+func TestGroupByIdle(t *testing.T) {
+	clock := newFakeClock()
+	in := make(chan string)
+	groups := GroupBy(in, func(a string) byte { return a[0] }, OpGroupIdle(time.Second), OpClock(clock))
+
+	in <- "a1"
+	a := <-groups
+	<-a.Items
+	clock.Advance(500 * time.Millisecond)
+
+	in <- "b1"
+	b := <-groups
+	<-b.Items
+	clock.Advance(500 * time.Millisecond) // a has now been idle for 1s
+
+	if _, ok := <-a.Items; ok {
+		t.Log("expected group a to be closed")
+		t.Fail()
+	}
+
+	in <- "a2"
+	a2 := <-groups
+	if a2.Key != 'a' || <-a2.Items != "a2" {
+		t.Log("expected a new group for a2")
+		t.Fail()
+	}
+
+	in <- "b2"
+	if e := <-b.Items; e != "b2" {
+		t.Logf("expected, %v, but got %v", "b2", e)
+		t.Fail()
+	}
+
+	close(in)
+	for _, g := range []Group[byte, string]{b, a2} {
+		if _, ok := <-g.Items; ok {
+			t.Logf("expected group %c to be closed", g.Key)
+			t.Fail()
+		}
+	}
+	if _, ok := <-groups; ok {
+		t.Log("expected groups to be closed")
+		t.Fail()
+	}
+}
+
+func TestGroupByCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	groups := GroupBy(in, func(a int) int { return a % 3 }, OpContext(ctx))
+
+	in <- 1
+	g := <-groups
+	<-g.Items
+	cancel()
+
+	for name, c := range map[string]<-chan int{"items": g.Items, "groups": Map(groups, func(g Group[int, int]) int { return g.Key })} {
+		select {
+		case _, ok := <-c:
+			if ok {
+				t.Logf("expected %s to be closed", name)
+				t.Fail()
+			}
+		case <-time.After(time.Second):
+			t.Logf("expected %s to be closed by now", name)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/mon"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// OpJitter makes time based sources, such as Interval, add a random duration between 0 and jitter to every wait, which
+// is useful to keep many of them from firing at the same time
+func OpJitter(jitter time.Duration) Option {
+	return func(s settings) settings {
+		s.jitter = jitter
+		return s
+	}
+}
+
+// OpStartDelay makes time based sources, such as Interval, wait for delay before the first tick instead of the regular
+// duration. A delay of 0 makes the first tick right away.
+func OpStartDelay(delay time.Duration) Option {
+	return func(s settings) settings {
+		s.startDelay = &delay
+		return s
+	}
+}
+
+// ticks writes next(n) onto the return chan for every tick n, starting at 0, until next returns false as its second
+// value. The wait before tick n is delay(n), or the start delay for the first tick if OpStartDelay is supplied.
+func ticks[A any](delay func(n int) time.Duration, next func(n int) (A, bool), options ...Option) <-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+
+	wait := func(n int) time.Duration {
+		d := delay(n)
+		if n == 0 && s.startDelay != nil {
+			d = *s.startDelay
+		}
+		if s.jitter > 0 {
+			d += time.Duration(rand.Int63n(int64(s.jitter)))
+		}
+		return d
+	}
+
+	clock := clockOf(s)
+	out := make(chan A, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer observeClosed(s)
+		defer close(out)
+
+		timer := clock.NewTimer(wait(0))
+		defer timer.Stop()
+
+		for n := 0; ; n++ {
+			select {
+			case <-s.done:
+				return
+			case <-timer.C():
+			}
+
+			var a A
+			var more bool
+			switch call(s, func() { a, more = next(n) }) {
+			case callSkip:
+				timer.Reset(wait(n + 1))
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, out, a) || !more {
+				return
+			}
+			timer.Reset(wait(n + 1))
+		}
+	}()
+	return out
+}
+
+// every returns a delay of d for every tick
+func every(d time.Duration) func(n int) time.Duration {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// Interval writes the number of the tick, starting at 0, onto the return chan every d. The wait for the next tick
+// starts once the previous one has been written, so a slow reader delays ticks rather than have them pile up.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time between ticks.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Interval(d time.Duration, options ...Option) <-chan int {
+	return ticks(every(d), func(n int) (int, bool) {
+		return n, true
+	}, options...)
+}
+
+// IntervalWith writes the number of the tick, starting at 0, onto the return chan every d. The wait for the next tick
+// starts once the previous one has been written, so a slow reader delays ticks rather than have them pile up.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time between ticks.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func IntervalWith(options ...Option) func(d time.Duration) <-chan int {
+	return func(d time.Duration) <-chan int {
+		return Interval(d, options...)
+	}
+}
+
+// Ticker writes the result of fn onto the return chan every d. The wait for the next tick starts once the previous one
+// has been written, so a slow reader delays ticks rather than have them pile up.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time between ticks.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Ticker[A any](d time.Duration, fn func() A, options ...Option) <-chan A {
+	return ticks(every(d), func(int) (A, bool) {
+		return fn(), true
+	}, options...)
+}
+
+// TickerWith writes the result of fn onto the return chan every d. The wait for the next tick starts once the previous
+// one has been written, so a slow reader delays ticks rather than have them pile up.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time between ticks.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func TickerWith[A any](options ...Option) func(d time.Duration, fn func() A) <-chan A {
+	return func(d time.Duration, fn func() A) <-chan A {
+		return Ticker(d, fn, options...)
+	}
+}
+
+// After writes v onto the return chan once d has passed, and then closes it.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time to wait.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func After[A any](d time.Duration, v A, options ...Option) <-chan A {
+	return ticks(every(d), func(int) (A, bool) {
+		return v, false
+	}, options...)
+}
+
+// AfterWith writes v onto the return chan once d has passed, and then closes it.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time to wait.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func AfterWith[A any](options ...Option) func(d time.Duration, v A) <-chan A {
+	return func(d time.Duration, v A) <-chan A {
+		return After(d, v, options...)
+	}
+}
+
+// Timer writes the time of the Clock onto the return chan once d has passed, and then closes it. It is the equivalent
+// of time.After for pipelines.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time to wait.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Timer(d time.Duration, options ...Option) <-chan time.Time {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	clock := clockOf(s)
+	return ticks(every(d), func(int) (time.Time, bool) {
+		return clock.Now(), false
+	}, options...)
+}
+
+// TimerWith writes the time of the Clock onto the return chan once d has passed, and then closes it.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time to wait.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func TimerWith(options ...Option) func(d time.Duration) <-chan time.Time {
+	return func(d time.Duration) <-chan time.Time {
+		return Timer(d, options...)
+	}
+}
+
+// doubling returns a delay of 0 for the first tick, and then d doubled for every following tick
+func doubling(d time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		if n == 0 {
+			return 0
+		}
+		wait := d
+		for i := 1; i < n && wait <= math.MaxInt64/2; i++ {
+			wait *= 2
+		}
+		return wait
+	}
+}
+
+// Retrying calls fn and writes its result onto the return chan, until fn succeeds or it has been called attempts
+// times, after which the return chan is closed. An attempts of 0 or less means no limit. The first call is made right
+// away and the wait between calls starts at backoff and is doubled after every failed call, e.g. with a backoff of
+// 100ms fn is called after 0, 100ms, 300ms, 700ms and so on.
+// Failed calls are written as well, so that they can be logged, and the last item tells whether fn succeeded.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time between calls.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Retrying[A any](attempts int, backoff time.Duration, fn func() (A, error), options ...Option) <-chan mon.Result[A] {
+	return ticks(doubling(backoff), func(n int) (mon.Result[A], bool) {
+		res := mon.TupleToResult(fn())
+		return res, !res.Ok() && (attempts < 1 || n+1 < attempts)
+	}, options...)
+}
+
+// RetryingWith calls fn and writes its result onto the return chan, until fn succeeds or it has been called attempts
+// times, after which the return chan is closed. An attempts of 0 or less means no limit. The first call is made right
+// away and the wait between calls starts at backoff and is doubled after every failed call.
+// Time is read from the Clock supplied by OpClock, and OpJitter and OpStartDelay changes the time between calls.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "done" channel is closed or the context.Done is closed, which is supplied in Option
+func RetryingWith[A any](options ...Option) func(attempts int, backoff time.Duration, fn func() (A, error)) <-chan mon.Result[A] {
+	return func(attempts int, backoff time.Duration, fn func() (A, error)) <-chan mon.Result[A] {
+		return Retrying(attempts, backoff, fn, options...)
+	}
+}
@@ -0,0 +1,181 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+// tickAt advances the clock to when, once a timer is due at that time, and returns what is then read from c
+func tickAt[A any](clock *fakeClock, when time.Duration, c <-chan A) A {
+	clock.BlockUntilDue(time.Unix(0, 0).Add(when))
+	clock.Advance(when - clock.Now().Sub(time.Unix(0, 0)))
+	return <-c
+}
+
+func TestInterval(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := Interval(time.Second, OpClock(clock), OpContext(ctx))
+
+	var res []int
+	for i := 1; i <= 3; i++ {
+		res = append(res, tickAt(clock, time.Duration(i)*time.Second, ticks))
+	}
+	exp := []int{0, 1, 2}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	cancel()
+	if _, ok := <-ticks; ok {
+		t.Log("expected ticks to be closed")
+		t.Fail()
+	}
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	if len(clock.timers) != 0 { // The timer is stopped before ticks is closed
+		t.Logf("expected the timer to be released, but got %d timers", len(clock.timers))
+		t.Fail()
+	}
+}
+
+func TestIntervalStartDelay(t *testing.T) {
+	clock := newFakeClock()
+	done := make(chan struct{})
+	defer close(done)
+	ticks := Interval(time.Second, OpClock(clock), OpDone(done), OpStartDelay(0))
+
+	if n := <-ticks; n != 0 {
+		t.Logf("expected, %v, but got %v", 0, n)
+		t.Fail()
+	}
+	if n := tickAt(clock, time.Second, ticks); n != 1 {
+		t.Logf("expected, %v, but got %v", 1, n)
+		t.Fail()
+	}
+}
+
+func TestIntervalJitter(t *testing.T) {
+	clock := newFakeClock()
+	done := make(chan struct{})
+	defer close(done)
+	ticks := Interval(time.Second, OpClock(clock), OpDone(done), OpJitter(time.Second))
+
+	clock.BlockUntil(1)
+	var due time.Time
+	for due.IsZero() {
+		clock.mu.Lock()
+		for timer := range clock.timers {
+			due = timer.when
+		}
+		clock.mu.Unlock()
+	}
+	if wait := due.Sub(time.Unix(0, 0)); wait < time.Second || wait >= 2*time.Second {
+		t.Logf("expected a wait between 1s and 2s, but got %v", wait)
+		t.Fail()
+	}
+	clock.Advance(2 * time.Second)
+	if n := <-ticks; n != 0 {
+		t.Logf("expected, %v, but got %v", 0, n)
+		t.Fail()
+	}
+}
+
+func TestTicker(t *testing.T) {
+	clock := newFakeClock()
+	done := make(chan struct{})
+	defer close(done)
+	var calls int
+	ticks := Ticker(time.Minute, func() string {
+		calls++
+		return "tick"
+	}, OpClock(clock), OpDone(done))
+
+	for i := 1; i <= 2; i++ {
+		if e := tickAt(clock, time.Duration(i)*time.Minute, ticks); e != "tick" {
+			t.Logf("expected, %v, but got %v", "tick", e)
+			t.Fail()
+		}
+	}
+	if calls != 2 {
+		t.Logf("expected, %v, but got %v", 2, calls)
+		t.Fail()
+	}
+}
+
+func TestAfter(t *testing.T) {
+	clock := newFakeClock()
+	after := After(time.Hour, "late", OpClock(clock))
+
+	if e := tickAt(clock, time.Hour, after); e != "late" {
+		t.Logf("expected, %v, but got %v", "late", e)
+		t.Fail()
+	}
+	if _, ok := <-after; ok {
+		t.Log("expected after to be closed")
+		t.Fail()
+	}
+}
+
+func TestTimer(t *testing.T) {
+	clock := newFakeClock()
+	timer := Timer(time.Minute, OpClock(clock))
+
+	if at := tickAt(clock, time.Minute, timer); !at.Equal(time.Unix(60, 0)) {
+		t.Logf("expected, %v, but got %v", time.Unix(60, 0), at)
+		t.Fail()
+	}
+	if _, ok := <-timer; ok {
+		t.Log("expected timer to be closed")
+		t.Fail()
+	}
+}
+
+func TestRetrying(t *testing.T) {
+	clock := newFakeClock()
+	fail := errors.New("fail")
+	var calls int
+	results := Retrying(5, time.Second, func() (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, fail
+		}
+		return calls, nil
+	}, OpClock(clock))
+
+	if err := (<-results).Error(); err != fail {
+		t.Logf("expected, %v, but got %v", fail, err)
+		t.Fail()
+	}
+	if err := tickAt(clock, time.Second, results).Error(); err != fail {
+		t.Logf("expected, %v, but got %v", fail, err)
+		t.Fail()
+	}
+	if v, err := tickAt(clock, 3*time.Second, results).Get(); err != nil || v != 3 {
+		t.Logf("expected, %v, but got %v, %v", 3, v, err)
+		t.Fail()
+	}
+	if _, ok := <-results; ok {
+		t.Log("expected results to be closed after a success")
+		t.Fail()
+	}
+}
+
+func TestRetryingAttempts(t *testing.T) {
+	clock := newFakeClock()
+	fail := errors.New("fail")
+	results := Retrying(2, time.Second, func() (int, error) {
+		return 0, fail
+	}, OpClock(clock))
+
+	<-results
+	tickAt(clock, time.Second, results)
+	if _, ok := <-results; ok {
+		t.Log("expected results to be closed after 2 attempts")
+		t.Fail()
+	}
+}
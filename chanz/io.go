@@ -0,0 +1,153 @@
+package chanz
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// fromReader runs read in a goroutine and returns a func that waits for it to return, and then returns its error, or
+// the Panic if it panics
+func fromReader[A any](s settings, read func(out chan<- A) error) (<-chan A, func() error) {
+	out := make(chan A, s.buffer)
+	finished := make(chan struct{})
+	var err error
+	go func() {
+		defer close(finished)
+		defer recoverStage(s)
+		defer func() { // A panic is the error, even if it is recovered by OpRecover
+			if r := recover(); r != nil {
+				err = Panic{Value: r, Stack: debug.Stack()}
+				panic(r)
+			}
+		}()
+		defer observeClosed(s)
+		defer close(out)
+		err = read(out)
+		if err != nil && err != doneErr(s) && s.fail != nil {
+			s.fail(err)
+		}
+	}()
+	return out, func() error {
+		<-finished
+		return err
+	}
+}
+
+// FromReader reads r using a bufio.Scanner with the split func, and writes every token onto the return chan. If split
+// is nil, bufio.ScanLines is used, which gives one item per line.
+// The returned func waits for reading to stop, and then returns the error that stopped it, or nil if r was read until
+// io.EOF. The error is also reported to the Pipeline the stage is part of.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "r" is read until the end, "done" channel is closed or the context.Done is closed, which is supplied
+// in Option, in which case the context error, or ErrDone, is returned. A read from r that blocks is not interrupted.
+func FromReader(r io.Reader, split bufio.SplitFunc, options ...Option) (<-chan string, func() error) {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	return fromReader(s, func(out chan<- string) error {
+		scanner := bufio.NewScanner(r)
+		if split != nil {
+			scanner.Split(split)
+		}
+		for scanner.Scan() {
+			if !send(s, out, scanner.Text()) {
+				return doneErr(s)
+			}
+		}
+		return scanner.Err()
+	})
+}
+
+// FromJSONLines reads r as a stream of JSON values, such as newline delimited JSON, and writes every value decoded
+// into a T onto the return chan.
+// The returned func waits for reading to stop, and then returns the error that stopped it, or nil if r was read until
+// io.EOF. The error is also reported to the Pipeline the stage is part of.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "r" is read until the end, "done" channel is closed or the context.Done is closed, which is supplied
+// in Option, in which case the context error, or ErrDone, is returned. A read from r that blocks is not interrupted.
+func FromJSONLines[T any](r io.Reader, options ...Option) (<-chan T, func() error) {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	return fromReader(s, func(out chan<- T) error {
+		dec := json.NewDecoder(r)
+		for {
+			var t T
+			err := dec.Decode(&t)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !send(s, out, t) {
+				return doneErr(s)
+			}
+		}
+	})
+}
+
+// toWriter reads every item from c and writes it to w using write, it returns the first error. If write fails, the
+// rest of c is read and discarded in the background.
+func toWriter[A any](s settings, c <-chan A, write func(a A) error) error {
+	for {
+		e, more, err := receiveErr(s, c)
+		if err != nil {
+			if s.drain {
+				go drain(s, c)
+			}
+			return err
+		}
+		if !more {
+			return nil
+		}
+		if err := write(e); err != nil {
+			if s.fail != nil {
+				s.fail(err)
+			}
+			DropAll(c, true)
+			return err
+		}
+	}
+}
+
+// ToJSONLines reads every item from c and writes it to w as JSON followed by a newline, which is newline delimited
+// JSON. It returns once c is closed, or with the first error from encoding an item or writing to w, in which case the
+// rest of "c" is read and discarded in the background, so that stages upstream are not left blocked.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option, in which
+// case the context error, or ErrDone, is returned.
+func ToJSONLines[T any](w io.Writer, c <-chan T, options ...Option) error {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	enc := json.NewEncoder(w)
+	return toWriter(s, c, func(t T) error {
+		return enc.Encode(t)
+	})
+}
+
+// ToWriter reads every item from c and writes it to w, formatted according to format as by fmt.Fprintf, such as
+// "%v\n". It returns once c is closed, or with the first error from writing to w, in which case the rest of "c" is
+// read and discarded in the background, so that stages upstream are not left blocked.
+// It will stop once "c", "done" channel is closed or the context.Done is closed, which is supplied in Option, in which
+// case the context error, or ErrDone, is returned.
+func ToWriter[A any](w io.Writer, c <-chan A, format string, options ...Option) error {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	return toWriter(s, c, func(a A) error {
+		_, err := fmt.Fprintf(w, format, a)
+		return err
+	})
+}
@@ -0,0 +1,172 @@
+package chanz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/modfin/henry/slicez"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type record struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestFromReader(t *testing.T) {
+	lines, errf := FromReader(strings.NewReader("a\nb b\n\nc"), nil)
+	res := Collect(lines)
+	exp := []string{"a", "b b", "", "c"}
+	if !slicez.Equal(res, exp) || errf() != nil {
+		t.Logf("expected, %q, but got %q, %v", exp, res, errf())
+		t.Fail()
+	}
+
+	words, errf := FromReader(strings.NewReader("a\nb b\n\nc"), bufio.ScanWords)
+	res = Collect(words)
+	exp = []string{"a", "b", "b", "c"}
+	if !slicez.Equal(res, exp) || errf() != nil {
+		t.Logf("expected, %q, but got %q, %v", exp, res, errf())
+		t.Fail()
+	}
+}
+
+func TestFromReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewPipeline(context.Background())
+	lines, errf := FromReader(iotest.ErrReader(boom), nil, p.Options()...)
+
+	if res := Collect(lines); len(res) != 0 || errf() != boom {
+		t.Logf("expected, %v, but got %q, %v", boom, res, errf())
+		t.Fail()
+	}
+	if p.Wait() != boom {
+		t.Logf("expected, %v, but got %v", boom, p.Wait())
+		t.Fail()
+	}
+}
+
+// panicReader panics on every read
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) {
+	panic("boom")
+}
+
+func TestFromReaderPanic(t *testing.T) {
+	lines, errf := FromReader(panicReader{}, nil, OpRecover(func(p Panic) bool { return false }))
+
+	Collect(lines)
+	if p, ok := errf().(Panic); !ok || p.Value != "boom" {
+		t.Logf("expected, %v, but got %v", Panic{Value: "boom"}, errf())
+		t.Fail()
+	}
+}
+
+func TestFromReaderCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, errf := FromReader(strings.NewReader(strings.Repeat("line\n", 100)), nil, OpContext(ctx))
+	<-lines
+	cancel() // Nothing is reading lines, so the stage is bound to see that ctx is cancelled
+
+	if !errors.Is(errf(), context.Canceled) {
+		t.Logf("expected, %v, but got %v", context.Canceled, errf())
+		t.Fail()
+	}
+}
+
+// failWriter fails every write with err
+type failWriter struct {
+	err error
+}
+
+func (w failWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestToWriterError(t *testing.T) {
+	boom := errors.New("boom")
+	in, finished := unbuffered(10)
+
+	if err := ToWriter(failWriter{boom}, in, "%v\n"); err != boom {
+		t.Logf("expected, %v, but got %v", boom, err)
+		t.Fail()
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Log("expected the rest of c to be drained")
+		t.Fail()
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	in := []record{{"a", 1}, {"b", 2}}
+	var buf bytes.Buffer
+	if err := ToJSONLines(&buf, Generate(in...)); err != nil {
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+	exp := "{\"name\":\"a\",\"age\":1}\n{\"name\":\"b\",\"age\":2}\n"
+	if buf.String() != exp {
+		t.Logf("expected, %q, but got %q", exp, buf.String())
+		t.Fail()
+	}
+
+	records, errf := FromJSONLines[record](&buf)
+	res := Collect(records)
+	if !slicez.Equal(res, in) || errf() != nil {
+		t.Logf("expected, %v, but got %v, %v", in, res, errf())
+		t.Fail()
+	}
+}
+
+func TestFromJSONLinesError(t *testing.T) {
+	records, errf := FromJSONLines[record](strings.NewReader("{\"name\":\"a\",\"age\":1}\n{\"name\":"))
+	res := Collect(records)
+	exp := []record{{"a", 1}}
+	if !slicez.Equal(res, exp) || errf() == nil {
+		t.Logf("expected, %v and an error, but got %v, %v", exp, res, errf())
+		t.Fail()
+	}
+}
+
+type failingWriter struct {
+	n   int
+	err error
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.n == 0 {
+		return 0, f.err
+	}
+	f.n--
+	return len(p), nil
+}
+
+func TestToWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ToWriter(&buf, Generate(1, 2, 3), "%d;"); err != nil || buf.String() != "1;2;3;" {
+		t.Logf("expected, %v, but got %v, %v", "1;2;3;", buf.String(), err)
+		t.Fail()
+	}
+
+	boom := errors.New("boom")
+	if err := ToWriter(&failingWriter{n: 1, err: boom}, Generate(1, 2, 3), "%d\n"); err != boom {
+		t.Logf("expected, %v, but got %v", boom, err)
+		t.Fail()
+	}
+}
+
+func TestToWriterDone(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	if err := ToJSONLines(&bytes.Buffer{}, make(chan int), OpDone(done)); err != ErrDone {
+		t.Logf("expected, %v, but got %v", ErrDone, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,291 @@
+package chanz
+
+import (
+	"fmt"
+	"github.com/modfin/henry/mon"
+	"time"
+)
+
+// JoinMode decides which items JoinWindow writes, see OpJoin
+type JoinMode int
+
+const (
+	JoinInner JoinMode = iota
+	JoinLeftOuter
+	JoinFullOuter
+)
+
+// OpJoin sets which items JoinWindow writes. JoinInner only writes pairs of matching items, JoinLeftOuter also writes
+// items from left that expire without a match, and JoinFullOuter also writes items from either side that expire
+// without a match. Default is JoinInner.
+func OpJoin(mode JoinMode) Option {
+	return func(s settings) settings {
+		s.join = mode
+		return s
+	}
+}
+
+// OpJoinExpiredLeft sets a chan that JoinWindow writes items from left to, that expire without a match and are not
+// written onto the return chan, as decided by OpJoin. The type of the chan must be the same as the type of the items
+// of left, or JoinWindow panics. Writing to expired blocks, so it should be buffered or read by someone.
+func OpJoinExpiredLeft[L any](expired chan<- L) Option {
+	return func(s settings) settings {
+		s.joinExpiredL = expired
+		return s
+	}
+}
+
+// OpJoinExpiredRight sets a chan that JoinWindow writes items from right to, that expire without a match and are not
+// written onto the return chan, as decided by OpJoin. The type of the chan must be the same as the type of the items
+// of right, or JoinWindow panics. Writing to expired blocks, so it should be buffered or read by someone.
+func OpJoinExpiredRight[R any](expired chan<- R) Option {
+	return func(s settings) settings {
+		s.joinExpiredR = expired
+		return s
+	}
+}
+
+// expiredChan returns the chan set by OpJoinExpiredLeft or OpJoinExpiredRight, or nil if there is none. It panics if
+// the chan is of the wrong type, since that is a mistake in the code calling JoinWindow.
+func expiredChan[A any](c any, option string) chan<- A {
+	if c == nil {
+		return nil
+	}
+	expired, ok := c.(chan<- A)
+	if !ok {
+		var zero A
+		panic(fmt.Sprintf("chanz: %s is given a %T, but JoinWindow expects a chan<- %T", option, c, zero))
+	}
+	return expired
+}
+
+// expireTo writes v to expired, unless expired is nil, it returns false if the stage is to stop
+func expireTo[A any](s settings, expired chan<- A, v A) bool {
+	if expired == nil {
+		return true
+	}
+	select {
+	case <-s.done:
+		return false
+	case <-stopped(s):
+		return false
+	case expired <- v:
+		return true
+	}
+}
+
+type joinItem[A any, K comparable] struct {
+	val     A
+	key     K
+	at      time.Time
+	matched bool
+}
+
+// joinSide is the items from one side of a join that are within the window, in the order they were read
+type joinSide[A any, K comparable] struct {
+	items []*joinItem[A, K]
+	byKey map[K][]*joinItem[A, K]
+}
+
+func (j *joinSide[A, K]) add(item *joinItem[A, K]) {
+	j.items = append(j.items, item)
+	j.byKey[item.key] = append(j.byKey[item.key], item)
+}
+
+// expire removes, and returns, the oldest item if it has expired as of now, or nil otherwise
+func (j *joinSide[A, K]) expire(now time.Time, window time.Duration, all bool) *joinItem[A, K] {
+	if len(j.items) == 0 || (!all && j.items[0].at.Add(window).After(now)) {
+		return nil
+	}
+	item := j.items[0]
+	j.items = j.items[1:]
+	if same := j.byKey[item.key]; len(same) > 1 {
+		j.byKey[item.key] = same[1:]
+	} else {
+		delete(j.byKey, item.key)
+	}
+	return item
+}
+
+// JoinWindow joins the items from left and right that has the same key, according to leftKey and rightKey, and that
+// are read within window of each other. Every pair of matching items is passed to joiner, and the result is written
+// onto the return chan. Items that expire without a match are passed to joiner, with None as the other side, if
+// OpJoin says so, and otherwise written to the chan set by OpJoinExpiredLeft or OpJoinExpiredRight, if any. Once both
+// left and right are closed, every item still within the window expires.
+// Time is read from the Clock supplied by OpClock, which defaults to the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "left" and "right", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func JoinWindow[L any, R any, K comparable, O any](left <-chan L, right <-chan R, leftKey func(l L) K, rightKey func(r R) K, window time.Duration, joiner func(l mon.Option[L], r mon.Option[R]) O, options ...Option) <-chan O {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+	expiredLeft := expiredChan[L](s.joinExpiredL, "OpJoinExpiredLeft")
+	expiredRight := expiredChan[R](s.joinExpiredR, "OpJoinExpiredRight")
+
+	clock := clockOf(s)
+	out := make(chan O, s.buffer)
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, right)
+		defer drain(s, left)
+		defer observeClosed(s)
+		defer close(out)
+
+		ls := &joinSide[L, K]{byKey: map[K][]*joinItem[L, K]{}}
+		rs := &joinSide[R, K]{byKey: map[K][]*joinItem[R, K]{}}
+
+		// emit passes a pair to joiner and writes the result, it returns false if the stage is to stop
+		emit := func(l mon.Option[L], r mon.Option[R]) bool {
+			var o O
+			switch call(s, func() { o = joiner(l, r) }) {
+			case callSkip:
+				return true
+			case callStop:
+				return false
+			}
+			return send(s, out, o)
+		}
+		// expire removes every item that has expired as of now, or every item if all is set
+		expire := func(now time.Time, all bool) bool {
+			for item := ls.expire(now, window, all); item != nil; item = ls.expire(now, window, all) {
+				if item.matched {
+					continue
+				}
+				if s.join != JoinInner {
+					if !emit(mon.Some(item.val), mon.None[R]()) {
+						return false
+					}
+				} else if !expireTo(s, expiredLeft, item.val) {
+					return false
+				}
+			}
+			for item := rs.expire(now, window, all); item != nil; item = rs.expire(now, window, all) {
+				if item.matched {
+					continue
+				}
+				if s.join == JoinFullOuter {
+					if !emit(mon.None[L](), mon.Some(item.val)) {
+						return false
+					}
+				} else if !expireTo(s, expiredRight, item.val) {
+					return false
+				}
+			}
+			return true
+		}
+
+		var timer ClockTimer
+		var timeout <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		// arm makes the timer fire once the oldest item, as of now, expires
+		arm := func(now time.Time) {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			var oldest time.Time
+			if len(ls.items) > 0 {
+				oldest = ls.items[0].at
+			}
+			if len(rs.items) > 0 && (oldest.IsZero() || rs.items[0].at.Before(oldest)) {
+				oldest = rs.items[0].at
+			}
+			if oldest.IsZero() {
+				return
+			}
+			timer = clock.NewTimer(oldest.Add(window).Sub(now))
+			timeout = timer.C()
+		}
+
+		lin, rin := left, right
+		for lin != nil || rin != nil {
+			select {
+			case <-s.done:
+				return
+			case now := <-timeout:
+				timer, timeout = nil, nil
+				if !expire(now, false) {
+					return
+				}
+				arm(now)
+			case l, ok := <-lin:
+				if !ok {
+					lin = nil
+					continue
+				}
+				observeReceived(s)
+				now := clock.Now()
+				if !expire(now, false) {
+					return
+				}
+				var k K
+				switch call(s, func() { k = leftKey(l) }) {
+				case callSkip:
+					continue
+				case callStop:
+					return
+				}
+				item := &joinItem[L, K]{val: l, key: k, at: now}
+				for _, r := range rs.byKey[k] {
+					item.matched, r.matched = true, true
+					if !emit(mon.Some(l), mon.Some(r.val)) {
+						return
+					}
+				}
+				ls.add(item)
+				if timer == nil {
+					arm(now)
+				}
+			case r, ok := <-rin:
+				if !ok {
+					rin = nil
+					continue
+				}
+				observeReceived(s)
+				now := clock.Now()
+				if !expire(now, false) {
+					return
+				}
+				var k K
+				switch call(s, func() { k = rightKey(r) }) {
+				case callSkip:
+					continue
+				case callStop:
+					return
+				}
+				item := &joinItem[R, K]{val: r, key: k, at: now}
+				for _, l := range ls.byKey[k] {
+					item.matched, l.matched = true, true
+					if !emit(mon.Some(l.val), mon.Some(r)) {
+						return
+					}
+				}
+				rs.add(item)
+				if timer == nil {
+					arm(now)
+				}
+			}
+		}
+		expire(time.Time{}, true)
+	}()
+	return out
+}
+
+// JoinWindowWith joins the items from left and right that has the same key, according to leftKey and rightKey, and
+// that are read within window of each other. Every pair of matching items is passed to joiner, and the result is
+// written onto the return chan. Items that expire without a match are passed to joiner, with None as the other side,
+// if OpJoin says so, and otherwise written to the chan set by OpJoinExpiredLeft or OpJoinExpiredRight, if any.
+// Time is read from the Clock supplied by OpClock, which defaults to the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "left" and "right", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func JoinWindowWith[L any, R any, K comparable, O any](options ...Option) func(left <-chan L, right <-chan R, leftKey func(l L) K, rightKey func(r R) K, window time.Duration, joiner func(l mon.Option[L], r mon.Option[R]) O) <-chan O {
+	return func(left <-chan L, right <-chan R, leftKey func(l L) K, rightKey func(r R) K, window time.Duration, joiner func(l mon.Option[L], r mon.Option[R]) O) <-chan O {
+		return JoinWindow(left, right, leftKey, rightKey, window, joiner, options...)
+	}
+}
@@ -0,0 +1,136 @@
+package chanz
+
+import (
+	"context"
+	"github.com/modfin/henry/mon"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+type joinPart struct {
+	key int
+	val string
+}
+
+func joinParts(l mon.Option[joinPart], r mon.Option[joinPart]) string {
+	var none joinPart
+	none.val = "_"
+	return l.OrElse(none).val + "+" + r.OrElse(none).val
+}
+
+func partKey(p joinPart) int {
+	return p.key
+}
+
+func TestJoinWindow(t *testing.T) {
+	tests := []struct {
+		mode         JoinMode
+		exp          []string
+		expiredLeft  []joinPart
+		expiredRight []joinPart
+	}{
+		{JoinInner, []string{"l1+r1", "l1+r1b"}, []joinPart{{2, "l2"}}, []joinPart{{3, "r3"}}},
+		{JoinLeftOuter, []string{"l1+r1", "l1+r1b", "l2+_"}, nil, []joinPart{{3, "r3"}}},
+		{JoinFullOuter, []string{"l1+r1", "l1+r1b", "l2+_", "_+r3"}, nil, nil},
+	}
+
+	for _, test := range tests {
+		clock := newFakeClock()
+		left, right := make(chan joinPart), make(chan joinPart)
+		expiredLeft, expiredRight := make(chan joinPart, 10), make(chan joinPart, 10)
+		out := JoinWindow(left, right, partKey, partKey, 10*time.Second, joinParts,
+			OpClock(clock), OpJoin(test.mode), OpJoinExpiredLeft[joinPart](expiredLeft),
+			OpJoinExpiredRight[joinPart](expiredRight))
+
+		var res []string
+		left <- joinPart{1, "l1"}
+		right <- joinPart{1, "r1"}
+		res = append(res, <-out)
+		left <- joinPart{2, "l2"}
+		right <- joinPart{3, "r3"}
+		right <- joinPart{1, "r1b"}
+		res = append(res, <-out)
+
+		close(left) // Once both sides are closed every item expires
+		close(right)
+		res = append(res, Collect(out)...)
+		close(expiredLeft)
+		close(expiredRight)
+
+		if !slicez.Equal(res, test.exp) {
+			t.Logf("expected, %v, but got %v", test.exp, res)
+			t.Fail()
+		}
+		if exp := Collect(expiredLeft); !slicez.Equal(exp, test.expiredLeft) {
+			t.Logf("expected, %v, but got %v", test.expiredLeft, exp)
+			t.Fail()
+		}
+		if exp := Collect(expiredRight); !slicez.Equal(exp, test.expiredRight) {
+			t.Logf("expected, %v, but got %v", test.expiredRight, exp)
+			t.Fail()
+		}
+	}
+}
+
+func TestJoinWindowExpires(t *testing.T) {
+	clock := newFakeClock()
+	left, right := make(chan joinPart), make(chan joinPart)
+	expiredLeft, expiredRight := make(chan joinPart, 10), make(chan joinPart, 10)
+	out := JoinWindow(left, right, partKey, partKey, 10*time.Second, joinParts, OpClock(clock),
+		OpJoinExpiredLeft[joinPart](expiredLeft), OpJoinExpiredRight[joinPart](expiredRight))
+
+	left <- joinPart{1, "l1"}
+	clock.BlockUntilDue(time.Unix(10, 0))
+	clock.Advance(15 * time.Second)
+	if e := <-expiredLeft; e != (joinPart{1, "l1"}) {
+		t.Logf("expected, %v, but got %v", joinPart{1, "l1"}, e)
+		t.Fail()
+	}
+
+	right <- joinPart{1, "r1"} // Too late to match l1
+	close(left)
+	close(right)
+	if res := Collect(out); len(res) != 0 {
+		t.Logf("expected no joined items, but got %v", res)
+		t.Fail()
+	}
+	if e := <-expiredRight; e != (joinPart{1, "r1"}) {
+		t.Logf("expected, %v, but got %v", joinPart{1, "r1"}, e)
+		t.Fail()
+	}
+}
+
+func TestJoinWindowExpiredType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Log("expected a panic for an expired chan of the wrong type")
+			t.Fail()
+		}
+	}()
+	JoinWindow(make(chan joinPart), make(chan joinPart), partKey, partKey, time.Second, joinParts,
+		OpJoinExpiredLeft[string](make(chan string)))
+}
+
+func TestJoinWindowCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	left, right := make(chan joinPart), make(chan joinPart)
+	out := JoinWindow(left, right, partKey, partKey, time.Minute, joinParts, OpContext(ctx))
+
+	left <- joinPart{1, "l1"}
+	right <- joinPart{1, "r1"}
+	<-out
+	left <- joinPart{2, "l2"} // Left waiting in the window as the join is cancelled
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Log("expected out to be closed")
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Log("expected out to be closed by now")
+		t.Fail()
+	}
+}
@@ -0,0 +1,251 @@
+package chanz
+
+import "container/heap"
+
+type mergeHead[A any] struct {
+	val A
+	src int
+}
+
+type mergeHeap[A any] struct {
+	heads []mergeHead[A]
+	less  func(a, b A) bool
+}
+
+func (h *mergeHeap[A]) Len() int {
+	return len(h.heads)
+}
+func (h *mergeHeap[A]) Less(i, j int) bool {
+	a, b := h.heads[i], h.heads[j]
+	if h.less(a.val, b.val) {
+		return true
+	}
+	if h.less(b.val, a.val) {
+		return false
+	}
+	return a.src < b.src // Equal items are emitted in the order of the input chans
+}
+func (h *mergeHeap[A]) Swap(i, j int) {
+	h.heads[i], h.heads[j] = h.heads[j], h.heads[i]
+}
+func (h *mergeHeap[A]) Push(x any) {
+	h.heads = append(h.heads, x.(mergeHead[A]))
+}
+func (h *mergeHeap[A]) Pop() any {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
+
+// MergeSorted merges chans that each are sorted according to less into one sorted chan. It keeps one item from each
+// input chan and always writes the least one, which means that it has to wait for every open input chan to have an
+// item, or be closed, before anything can be written.
+func MergeSorted[A any](less func(a, b A) bool, cs ...<-chan A) <-chan A {
+	return MergeSortedWith[A]()(less, cs...)
+}
+
+// MergeSortedWith merges chans that each are sorted according to less into one sorted chan. It keeps one item from
+// each input chan and always writes the least one, which means that it has to wait for every open input chan to have
+// an item, or be closed, before anything can be written. A panic in less stops the stage, even if the recover handler
+// supplied by OpRecover returns true, since the held items can then no longer be ordered.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once all "cs", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func MergeSortedWith[A any](options ...Option) func(less func(a, b A) bool, cs ...<-chan A) <-chan A {
+	return func(less func(a, b A) bool, cs ...<-chan A) <-chan A {
+		var s settings
+		for _, o := range options {
+			s = o(s)
+		}
+		s = stoppable(s)
+
+		out := make(chan A, s.buffer)
+		go func() {
+			defer recoverStage(s)
+			defer drain(s, cs...)
+			defer observeClosed(s)
+			defer close(out)
+
+			// ordered runs f, which calls less, and stops the stage on a panic in it, even if the recover handler
+			// would skip the item, since the held items can then no longer be ordered
+			h := &mergeHeap[A]{less: less}
+			ordered := func(f func()) bool {
+				if call(s, f) == callOk {
+					return true
+				}
+				s.stop.stop()
+				return false
+			}
+			receive := func(i int) bool {
+				select {
+				case <-s.done:
+					return false
+				case <-stopped(s):
+					return false
+				case e, ok := <-cs[i]:
+					if !ok {
+						return true
+					}
+					observeReceived(s)
+					return ordered(func() { heap.Push(h, mergeHead[A]{val: e, src: i}) })
+				}
+			}
+
+			for i := range cs {
+				if !receive(i) {
+					return
+				}
+			}
+			for h.Len() > 0 {
+				var head mergeHead[A]
+				if !ordered(func() { head = heap.Pop(h).(mergeHead[A]) }) {
+					return
+				}
+				if !send(s, out, head.val) {
+					return
+				}
+				if !receive(head.src) {
+					return
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// OpStarvationLimit lets PriorityMerge take an item from a lower priority chan after n items in a row has been taken
+// from higher priority chans while the lower priority item was waiting. Default is 0, meaning no limit.
+func OpStarvationLimit(n int) Option {
+	return func(s settings) settings {
+		s.starvation = n
+		return s
+	}
+}
+
+// PriorityMerge will merge all input from input channels into one output channel. Whenever items are waiting on more
+// than one input chan, the item from the chan with the lowest index in cs is taken first.
+func PriorityMerge[A any](cs ...<-chan A) <-chan A {
+	return PriorityMergeWith[A]()(cs...)
+}
+
+// PriorityMergeWith will merge all input from input channels into one output channel. Whenever items are waiting on
+// more than one input chan, the item from the chan with the lowest index in cs is taken first. To keep lower priority
+// chans from starving, OpStarvationLimit can be supplied.
+// Each input chan is read concurrently and at most two items per input chan are held while waiting.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once all "cs", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func PriorityMergeWith[A any](options ...Option) func(cs ...<-chan A) <-chan A {
+	return func(cs ...<-chan A) <-chan A {
+		var s settings
+		for _, o := range options {
+			s = o(s)
+		}
+
+		out := make(chan A, s.buffer)
+		ready := make(chan struct{}, 1)
+		signal := func() {
+			select {
+			case ready <- struct{}{}:
+			default:
+			}
+		}
+
+		heads := make([]chan A, len(cs))
+		forward := func(c <-chan A, head chan A) {
+			defer signal()
+			defer drain(s, c)
+			defer close(head)
+			for {
+				e, more := receive(s, c)
+				if !more {
+					return
+				}
+				select {
+				case <-s.done:
+					return
+				case head <- e:
+				}
+				signal()
+			}
+		}
+		for i, c := range cs {
+			heads[i] = make(chan A, 1)
+			go forward(c, heads[i])
+		}
+
+		go func() {
+			defer observeClosed(s)
+			defer close(out)
+
+			n := len(cs)
+			waiting := make([]A, n)
+			has := make([]bool, n)
+			seq := make([]int, n)
+			var open, loaded, starved = n, 0, 0
+
+			load := func() {
+				for i, head := range heads {
+					if has[i] || head == nil {
+						continue
+					}
+					select {
+					case e, ok := <-head:
+						if !ok {
+							heads[i] = nil
+							open--
+							continue
+						}
+						waiting[i], has[i], seq[i] = e, true, loaded
+						loaded++
+					default:
+					}
+				}
+			}
+
+			for {
+				load()
+				pick := -1
+				for i := range has {
+					if has[i] {
+						pick = i
+						break
+					}
+				}
+
+				if pick == -1 {
+					if open == 0 {
+						return
+					}
+					select {
+					case <-s.done:
+						return
+					case <-ready:
+					}
+					continue
+				}
+
+				if s.starvation > 0 {
+					oldest := -1
+					for j := pick + 1; j < n; j++ {
+						if has[j] && (oldest == -1 || seq[j] < seq[oldest]) {
+							oldest = j
+						}
+					}
+					switch {
+					case oldest == -1:
+						starved = 0
+					case starved >= s.starvation:
+						pick, starved = oldest, 0
+					default:
+						starved++
+					}
+				}
+
+				if !send(s, out, waiting[pick]) {
+					return
+				}
+				has[pick] = false
+			}
+		}()
+		return out
+	}
+}
@@ -0,0 +1,101 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/compare"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+func TestMergeSorted(t *testing.T) {
+	merged := MergeSorted(compare.Less[int],
+		Generate(1, 4, 7, 10),
+		Generate(2, 5),
+		Generate[int](),
+		Generate(0, 3, 6, 8, 9, 11, 12),
+	)
+
+	res := Collect(merged)
+	exp := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestMergeSortedDone(t *testing.T) {
+	done := make(chan struct{})
+	never := make(chan int)
+	merged := MergeSortedWith[int](OpDone(done))(compare.Less[int], Generate(1, 2, 3), never)
+
+	close(done)
+	select {
+	case _, ok := <-merged:
+		if ok {
+			t.Log("expected nothing to be written while waiting on an input")
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Log("expected merged chan to be closed by now")
+		t.Fail()
+	}
+}
+
+func bufferedOf[A any](items ...A) <-chan A {
+	c := make(chan A, len(items))
+	for _, e := range items {
+		c <- e
+	}
+	close(c)
+	return c
+}
+
+// collectSlowly reads from c, giving the inputs of the stage time to have items waiting between each read
+func collectSlowly[A any](c <-chan A) []A {
+	var res []A
+	for {
+		time.Sleep(5 * time.Millisecond)
+		e, ok := <-c
+		if !ok {
+			return res
+		}
+		res = append(res, e)
+	}
+}
+
+func TestPriorityMerge(t *testing.T) {
+	merged := PriorityMerge(bufferedOf(1, 2, 3), bufferedOf(10, 20, 30))
+
+	// The first item is picked before every input has an item waiting
+	res := collectSlowly(merged)[1:]
+	for i := 1; i < len(res); i++ {
+		if res[i-1] >= 10 && res[i] < 10 {
+			t.Logf("expected high priority items before low priority items, but got %v", res)
+			t.Fail()
+		}
+	}
+	if len(res) != 5 {
+		t.Logf("expected 6 items, but got %v", len(res)+1)
+		t.Fail()
+	}
+}
+
+func TestPriorityMergeStarvationLimit(t *testing.T) {
+	merged := PriorityMergeWith[int](OpStarvationLimit(2))(bufferedOf(1, 2, 3, 4, 5, 6, 7, 8), bufferedOf(10, 20))
+
+	res := collectSlowly(merged)
+	var highs int
+	for i, e := range res[1:] {
+		if e >= 10 {
+			highs = 0
+			continue
+		}
+		highs++
+		lowsLeft := len(slicez.Filter(res[i+1:], func(a int) bool { return a >= 10 }))
+		if highs > 2 && lowsLeft > 0 {
+			t.Logf("expected at most 2 high priority items in a row, but got %v", res)
+			t.Fail()
+			return
+		}
+	}
+}
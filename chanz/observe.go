@@ -0,0 +1,82 @@
+package chanz
+
+import "time"
+
+// Observer is notified about what goes on inside a stage, which is useful for finding out which stage in a pipeline is
+// the bottleneck. Every callback is given the name of the stage, supplied by OpName. Callbacks are made from the
+// goroutines of the stage, so they must be safe for concurrent use and should return quickly.
+type Observer interface {
+	// Received is called when the stage has read an item from one of its input chans
+	Received(stage string)
+	// Emitted is called when the stage has written an item to one of its output chans
+	Emitted(stage string)
+	// Processed is called with the time spent in a user supplied func, such as the mapper passed to Map
+	Processed(stage string, d time.Duration)
+	// Blocked is called with the time spent waiting for room to write an item to an output chan
+	Blocked(stage string, d time.Duration)
+	// Closed is called when the stage has stopped and closed its output chans
+	Closed(stage string)
+}
+
+// OpObserver sets an Observer that is notified about the items received, emitted and processed by a stage
+func OpObserver(observer Observer) Option {
+	return func(s settings) settings {
+		s.observer = observer
+		return s
+	}
+}
+
+// OpName sets the name a stage is reported as to the Observer supplied by OpObserver
+func OpName(name string) Option {
+	return func(s settings) settings {
+		s.name = name
+		return s
+	}
+}
+
+// send writes a to c. It returns false if "done" is closed before a could be written, which is supplied in Option
+func send[A any](s settings, c chan<- A, a A) bool {
+	if s.observer == nil {
+		select {
+		case <-s.done:
+			return false
+		case <-stopped(s):
+			return false
+		case c <- a:
+			return true
+		}
+	}
+
+	start := time.Now()
+	select {
+	case <-s.done:
+		s.observer.Blocked(s.name, time.Since(start))
+		return false
+	case <-stopped(s):
+		s.observer.Blocked(s.name, time.Since(start))
+		return false
+	case c <- a:
+	}
+	s.observer.Blocked(s.name, time.Since(start))
+	s.observer.Emitted(s.name)
+	return true
+}
+
+func observeReceived(s settings) {
+	if s.observer != nil {
+		s.observer.Received(s.name)
+	}
+}
+
+func observeEmitted(s settings) {
+	if s.observer != nil {
+		s.observer.Emitted(s.name)
+	}
+}
+
+// observeClosed is deferred by stages, to run once their output chans are closed
+func observeClosed(s settings) {
+	if s.observer != nil {
+		s.observer.Closed(s.name)
+	}
+}
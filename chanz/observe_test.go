@@ -0,0 +1,100 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+func TestObserverMap(t *testing.T) {
+	col := NewCollector()
+	mapped := Map(Generate(1, 2, 3, 4), func(a int) int {
+		time.Sleep(time.Millisecond)
+		return a * 2
+	}, OpObserver(col), OpName("double"))
+
+	res := Collect(mapped)
+	exp := []int{2, 4, 6, 8}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	stats := col.Stage("double")
+	if stats.Received != 4 || stats.Emitted != 4 || stats.Closed != 1 {
+		t.Logf("expected 4 received, 4 emitted and 1 closed, but got %+v", stats)
+		t.Fail()
+	}
+	if stats.Processing.Count != 4 || stats.Processing.Mean() < time.Millisecond {
+		t.Logf("expected 4 processed items of at least 1ms, but got %d with mean %v", stats.Processing.Count, stats.Processing.Mean())
+		t.Fail()
+	}
+	if stats.Blocked.Count != 4 {
+		t.Logf("expected, %v, but got %v", 4, stats.Blocked.Count)
+		t.Fail()
+	}
+}
+
+func TestObserverBlocked(t *testing.T) {
+	col := NewCollector()
+	filtered := Filter(Generate(1, 2, 3), func(a int) bool { return a != 2 }, OpObserver(col), OpName("filter"))
+
+	var res []int
+	for e := range filtered {
+		time.Sleep(5 * time.Millisecond)
+		res = append(res, e)
+	}
+
+	stats := col.Stats()["filter"]
+	if stats.Received != 3 || stats.Emitted != 2 {
+		t.Logf("expected 3 received and 2 emitted, but got %+v", stats)
+		t.Fail()
+	}
+	if stats.Blocked.Max < 4*time.Millisecond {
+		t.Logf("expected to be blocked for at least 4ms, but got %v", stats.Blocked.Max)
+		t.Fail()
+	}
+}
+
+func TestObserverKeyedMap(t *testing.T) {
+	col := NewCollector()
+	res := Collect(KeyedMap(Generate(1, 2, 3, 4, 5, 6), 3, func(a int) int { return a % 3 }, func(a int) int { return a },
+		OpObserver(col), OpName("keyed")))
+	if len(res) != 6 {
+		t.Logf("expected, %v, but got %v", 6, len(res))
+		t.Fail()
+	}
+
+	stats := col.Stats()
+	for _, name := range []string{"keyed/shard", "keyed/map", "keyed/merge"} {
+		if stats[name].Emitted != 6 {
+			t.Logf("expected %s to emit 6, but got %+v", name, stats[name])
+			t.Fail()
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h histogram
+	for _, d := range []time.Duration{500 * time.Nanosecond, 3 * time.Microsecond, 3 * time.Microsecond, time.Millisecond} {
+		h.add(d)
+	}
+	snap := h.snapshot()
+
+	if snap.Count != 4 || snap.Max != time.Millisecond {
+		t.Logf("expected 4 items with max 1ms, but got %d with max %v", snap.Count, snap.Max)
+		t.Fail()
+	}
+	if snap.Buckets[0].Count != 1 || snap.Buckets[2].Count != 2 {
+		t.Logf("expected 1 item in the 1µs bucket and 2 in the 4µs bucket, but got %+v", snap.Buckets[:3])
+		t.Fail()
+	}
+	if q := snap.Quantile(0.5); q != 4*time.Microsecond {
+		t.Logf("expected, %v, but got %v", 4*time.Microsecond, q)
+		t.Fail()
+	}
+	if q := snap.Quantile(1); q != time.Millisecond {
+		t.Logf("expected, %v, but got %v", time.Millisecond, q)
+		t.Fail()
+	}
+}
@@ -0,0 +1,128 @@
+package chanz
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+)
+
+// Pipeline is a handle on a set of stages that share the same context, options and fate. Stages are added to the
+// pipeline by supplying them with the options returned by Options, such as
+//
+//	p := chanz.NewPipeline(ctx, chanz.OpBuffer(10))
+//	mapped := chanz.MapErr(in, parse, p.Options()...)
+//	chanz.Sink(p, mapped, store)
+//	err := p.Wait()
+//
+// Wait only waits for funcs started with Go, such as Sink, so the last stage of the pipeline should be consumed by one.
+//
+// The first error from an error aware stage, such as MapErr, the first panic in a stage and the first error returned
+// by a func started with Go cancels the context of the pipeline, which stops every stage in it.
+type Pipeline struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	options []Option
+
+	wg  sync.WaitGroup
+	mu  sync.Mutex
+	err error
+}
+
+// NewPipeline returns a Pipeline with a context derived from ctx. Every stage added to it inherits options.
+func NewPipeline(ctx context.Context, options ...Option) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{
+		ctx:     ctx,
+		cancel:  cancel,
+		options: options,
+	}
+}
+
+// Context returns the context shared by every stage in the pipeline, which is cancelled once the pipeline fails or is
+// cancelled
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Options returns the options that add a stage to the pipeline, which are the options of the pipeline followed by
+// extra. A stage supplied with them stops once the pipeline is cancelled, and reports errors and panics to it.
+func (p *Pipeline) Options(extra ...Option) []Option {
+	options := make([]Option, 0, len(p.options)+len(extra)+3)
+	options = append(options, p.options...)
+	options = append(options,
+		OpContext(p.ctx),
+		OpRecover(func(pnc Panic) bool {
+			p.fail(pnc)
+			return false
+		}),
+		func(s settings) settings {
+			s.fail = p.fail
+			return s
+		},
+	)
+	return append(options, extra...)
+}
+
+// Go runs fn in a new goroutine that is part of the pipeline. If fn returns an error, or panics, the pipeline is
+// cancelled and the error is returned by Wait.
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				p.fail(Panic{Value: r, Stack: debug.Stack()})
+			}
+		}()
+		if err := fn(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Cancel stops every stage in the pipeline, without it being reported as an error by Wait
+func (p *Pipeline) Cancel() {
+	p.cancel()
+}
+
+// Wait waits for every func started with Go, such as Sink, to return. It returns the first error, or panic, from a
+// stage or a func started with Go, if any. Stages added through Options are not waited for, so their output must be
+// consumed by a Sink, or a func started with Go, for Wait to wait for it. The pipeline is not cancelled by Wait, which
+// lets stages whose output is consumed elsewhere carry on, use Cancel to stop them.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	return p.Err()
+}
+
+// Err returns the first error, or panic, from a stage or a func started with Go, or nil if there has been none so far
+func (p *Pipeline) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *Pipeline) fail(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.cancel()
+}
+
+// Sink reads every item from in and applies fn to it, in a goroutine started with Go. If fn returns an error the
+// pipeline fails with it. It stops once "in" is closed or the pipeline is cancelled.
+func Sink[A any](p *Pipeline, in <-chan A, fn func(a A) error) {
+	s := settings{done: p.ctx.Done()}
+	p.Go(func(ctx context.Context) error {
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return nil
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/mon"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+// counter writes 0, 1, 2, ... onto the return chan until the pipeline is cancelled
+func counter(p *Pipeline) <-chan int {
+	out := make(chan int)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return nil
+			case out <- i:
+			}
+		}
+	})
+	return out
+}
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline(context.Background())
+	mapped := MapWith[int, int](p.Options()...)(Generate(1, 2, 3, 4), func(a int) int { return a * 2 })
+	filtered := FilterWith[int](p.Options()...)(mapped, func(a int) bool { return a != 4 })
+
+	var res []int
+	Sink(p, filtered, func(a int) error {
+		res = append(res, a)
+		return nil
+	})
+
+	if err := p.Wait(); err != nil {
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+	exp := []int{2, 6, 8}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestPipelineConsumedOutside(t *testing.T) {
+	p := NewPipeline(context.Background())
+	defer p.Cancel()
+	mapped := Map(Generate(1, 2, 3), func(a int) int { return a * 2 }, p.Options()...)
+
+	if err := p.Wait(); err != nil { // Nothing is started with Go, so there is nothing to wait for
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+	res := Collect(mapped)
+	exp := []int{2, 4, 6}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestPipelineInheritsOptions(t *testing.T) {
+	p := NewPipeline(context.Background(), OpBuffer(5))
+	defer p.Cancel()
+
+	mapped := Map(Generate(1), func(a int) int { return a }, p.Options()...)
+	if cap(mapped) != 5 {
+		t.Logf("expected, %v, but got %v", 5, cap(mapped))
+		t.Fail()
+	}
+	unbuffered := Map(Generate(1), func(a int) int { return a }, p.Options(OpBuffer(0))...)
+	if cap(unbuffered) != 0 {
+		t.Logf("expected, %v, but got %v", 0, cap(unbuffered))
+		t.Fail()
+	}
+}
+
+func TestPipelineStageError(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewPipeline(context.Background())
+
+	results := MapErr(counter(p), func(a int) (int, error) {
+		if a == 3 {
+			return 0, boom
+		}
+		return a, nil
+	}, p.Options()...)
+	Sink(p, results, func(r mon.Result[int]) error { return nil })
+
+	if err := p.Wait(); !errors.Is(err, boom) {
+		t.Logf("expected, %v, but got %v", boom, err)
+		t.Fail()
+	}
+	if p.Context().Err() == nil {
+		t.Log("expected the context of the pipeline to be cancelled")
+		t.Fail()
+	}
+}
+
+func TestPipelinePanic(t *testing.T) {
+	p := NewPipeline(context.Background())
+
+	mapped := Map(counter(p), func(a int) int {
+		if a == 3 {
+			panic("boom")
+		}
+		return a
+	}, p.Options()...)
+	Sink(p, mapped, func(a int) error { return nil })
+
+	var pnc Panic
+	if err := p.Wait(); !errors.As(err, &pnc) || pnc.Value != "boom" {
+		t.Logf("expected a recovered panic, but got %v", err)
+		t.Fail()
+	}
+}
+
+func TestPipelineSinkError(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewPipeline(context.Background())
+
+	Sink(p, Map(counter(p), func(a int) int { return a }, p.Options()...), func(a int) error {
+		if a == 5 {
+			return boom
+		}
+		return nil
+	})
+
+	if err := p.Wait(); err != boom {
+		t.Logf("expected, %v, but got %v", boom, err)
+		t.Fail()
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	p := NewPipeline(context.Background())
+	Sink(p, Map(counter(p), func(a int) int { return a }, p.Options()...), func(a int) error { return nil })
+
+	time.AfterFunc(10*time.Millisecond, p.Cancel)
+
+	waited := make(chan error)
+	go func() {
+		waited <- p.Wait()
+	}()
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Logf("expected no error, but got %v", err)
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Log("expected the pipeline to be done by now")
+		t.Fail()
+	}
+}
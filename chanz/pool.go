@@ -0,0 +1,174 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/henry/mon"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrPoolClosed is the error of a Future from a task submitted to a Pool that has been shut down or stopped
+var ErrPoolClosed = errors.New("chanz: pool is closed")
+
+// PoolMetrics is a snapshot of the tasks of a Pool
+type PoolMetrics struct {
+	Queued    int // Tasks waiting for a worker
+	Running   int // Tasks being run by a worker
+	Completed int // Tasks that has been run, successfully or not
+}
+
+type poolTask[T any] struct {
+	fn      func() (T, error)
+	promise promise[T]
+}
+
+// Pool runs tasks using a fixed number of workers. Tasks are queued until a worker is free, and the result of each
+// task is available through the Future returned when it was submitted.
+type Pool[T any] struct {
+	queue   chan poolTask[T]
+	closing chan struct{} // Closed once the Pool no longer accepts tasks
+	quit    chan struct{} // Closed once the Pool is stopped
+	stop    sync.Once
+	drained sync.Once
+	workers sync.WaitGroup
+	submits sync.WaitGroup // Submit calls waiting for room in the queue
+
+	mu     sync.Mutex
+	closed bool
+
+	queued    int64
+	running   int64
+	completed int64
+}
+
+// NewPool returns a Pool that runs tasks using the given number of workers, with room for queueSize tasks waiting for
+// a free worker. If workers is less than 1, one worker is used.
+func NewPool[T any](workers int, queueSize int) *Pool[T] {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Pool[T]{
+		queue:   make(chan poolTask[T], queueSize),
+		closing: make(chan struct{}),
+		quit:    make(chan struct{}),
+	}
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool[T]) work() {
+	defer p.workers.Done()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case t, ok := <-p.queue:
+			if !ok {
+				return
+			}
+			atomic.AddInt64(&p.queued, -1)
+			select {
+			case <-p.quit:
+				t.promise.complete(mon.Err[T](ErrPoolClosed))
+				return
+			default:
+			}
+			p.run(t)
+		}
+	}
+}
+
+// run runs a task, a panic in it fails its Future rather than the worker
+func (p *Pool[T]) run(t poolTask[T]) {
+	atomic.AddInt64(&p.running, 1)
+	defer func() {
+		atomic.AddInt64(&p.running, -1)
+		atomic.AddInt64(&p.completed, 1)
+	}()
+	t.promise.complete(try(t.fn))
+}
+
+// Submit queues fn to be run by a worker, and returns a Future with its result. If the queue is full, Submit waits
+// until there is room. If the Pool is shut down or stopped, the Future fails with ErrPoolClosed.
+func (p *Pool[T]) Submit(fn func() (T, error)) Future[T] {
+	t := poolTask[T]{fn: fn, promise: newPromise[T]()}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		t.promise.complete(mon.Err[T](ErrPoolClosed))
+		return t.promise.future()
+	}
+	p.submits.Add(1)
+	p.mu.Unlock()
+	defer p.submits.Done()
+
+	atomic.AddInt64(&p.queued, 1)
+	select {
+	case <-p.closing:
+		atomic.AddInt64(&p.queued, -1)
+		t.promise.complete(mon.Err[T](ErrPoolClosed))
+	case p.queue <- t:
+	}
+	return t.promise.future()
+}
+
+// close makes Submit fail every new task, as well as those waiting for room in the queue, and then closes the queue,
+// which lets the workers return once it is empty
+func (p *Pool[T]) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+	}
+	p.mu.Unlock()
+
+	p.submits.Wait()
+	p.drained.Do(func() {
+		close(p.queue)
+	})
+}
+
+// Shutdown stops the Pool from accepting new tasks, and waits for every queued and running task to complete. If ctx
+// is cancelled before then, the Pool is stopped, as by Stop, and the error of ctx is returned.
+func (p *Pool[T]) Shutdown(ctx context.Context) error {
+	finished := make(chan struct{})
+	go func() {
+		p.close()
+		p.workers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		p.Stop()
+		return ctx.Err()
+	}
+}
+
+// Stop stops the Pool right away. Every queued task fails with ErrPoolClosed, while running tasks are left to
+// complete, since they can not be interrupted. Stop does not wait for them.
+func (p *Pool[T]) Stop() {
+	p.stop.Do(func() {
+		close(p.quit)
+	})
+	p.close()
+	for t := range p.queue {
+		atomic.AddInt64(&p.queued, -1)
+		t.promise.complete(mon.Err[T](ErrPoolClosed))
+	}
+}
+
+// Metrics returns the number of queued, running and completed tasks
+func (p *Pool[T]) Metrics() PoolMetrics {
+	return PoolMetrics{
+		Queued:    int(atomic.LoadInt64(&p.queued)),
+		Running:   int(atomic.LoadInt64(&p.running)),
+		Completed: int(atomic.LoadInt64(&p.completed)),
+	}
+}
@@ -0,0 +1,208 @@
+package chanz
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingTask returns a task that waits for release before returning v, and a chan that is closed once it has started
+func blockingTask(v int, release <-chan struct{}) (func() (int, error), <-chan struct{}) {
+	started := make(chan struct{})
+	return func() (int, error) {
+		close(started)
+		<-release
+		return v, nil
+	}, started
+}
+
+func TestPool(t *testing.T) {
+	var running, maxRunning int32
+	p := NewPool[int](3, 10)
+
+	var futures []Future[int]
+	for i := 0; i < 20; i++ {
+		i := i
+		futures = append(futures, p.Submit(func() (int, error) {
+			cur := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if cur <= m || atomic.CompareAndSwapInt32(&maxRunning, m, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return i * 2, nil
+		}))
+	}
+
+	for i, f := range futures {
+		if v, err := f.Await(context.Background()).Get(); err != nil || v != i*2 {
+			t.Logf("expected, %v, but got %v, %v", i*2, v, err)
+			t.Fail()
+		}
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+	if maxRunning > 3 {
+		t.Logf("expected at most 3 tasks at once, but got %d", maxRunning)
+		t.Fail()
+	}
+	if m := p.Metrics(); m != (PoolMetrics{Completed: 20}) {
+		t.Logf("expected, %+v, but got %+v", PoolMetrics{Completed: 20}, m)
+		t.Fail()
+	}
+}
+
+func TestPoolPanic(t *testing.T) {
+	p := NewPool[int](1, 0)
+	defer p.Stop()
+
+	failed := p.Submit(func() (int, error) { panic("boom") })
+	ok := p.Submit(func() (int, error) { return 1, nil })
+
+	var pnc Panic
+	if err := failed.Await(context.Background()).Error(); !errors.As(err, &pnc) || pnc.Value != "boom" {
+		t.Logf("expected a recovered panic, but got %v", err)
+		t.Fail()
+	}
+	if v, err := ok.Await(context.Background()).Get(); err != nil || v != 1 {
+		t.Logf("expected, %v, but got %v, %v", 1, v, err)
+		t.Fail()
+	}
+}
+
+func TestPoolShutdown(t *testing.T) {
+	p := NewPool[int](1, 5)
+	release := make(chan struct{})
+	task, started := blockingTask(0, release)
+	futures := []Future[int]{p.Submit(task)}
+	<-started
+	for i := 1; i < 4; i++ {
+		i := i
+		futures = append(futures, p.Submit(func() (int, error) { return i, nil }))
+	}
+
+	if m := p.Metrics(); m != (PoolMetrics{Queued: 3, Running: 1}) {
+		t.Logf("expected, %+v, but got %+v", PoolMetrics{Queued: 3, Running: 1}, m)
+		t.Fail()
+	}
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- p.Shutdown(context.Background())
+	}()
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Logf("expected no error, but got %v", err)
+		t.Fail()
+	}
+
+	for i, f := range futures {
+		if v, err := f.Await(context.Background()).Get(); err != nil || v != i {
+			t.Logf("expected, %v, but got %v, %v", i, v, err)
+			t.Fail()
+		}
+	}
+	if err := p.Submit(func() (int, error) { return 4, nil }).Await(context.Background()).Error(); err != ErrPoolClosed {
+		t.Logf("expected, %v, but got %v", ErrPoolClosed, err)
+		t.Fail()
+	}
+}
+
+func TestPoolShutdownTimeout(t *testing.T) {
+	p := NewPool[int](1, 5)
+	release := make(chan struct{})
+	defer close(release)
+	task, started := blockingTask(0, release)
+	running := p.Submit(task)
+	<-started
+	queued := p.Submit(func() (int, error) { return 1, nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Logf("expected, %v, but got %v", context.DeadlineExceeded, err)
+		t.Fail()
+	}
+	if err := queued.Await(context.Background()).Error(); err != ErrPoolClosed {
+		t.Logf("expected, %v, but got %v", ErrPoolClosed, err)
+		t.Fail()
+	}
+	select {
+	case <-running.Done():
+		t.Log("expected the running task to still be running")
+		t.Fail()
+	default:
+	}
+}
+
+func TestPoolStop(t *testing.T) {
+	p := NewPool[int](1, 5)
+	release := make(chan struct{})
+	task, started := blockingTask(7, release)
+	running := p.Submit(task)
+	<-started
+	queued := []Future[int]{
+		p.Submit(func() (int, error) { return 1, nil }),
+		p.Submit(func() (int, error) { return 2, nil }),
+	}
+
+	p.Stop()
+	for _, f := range queued {
+		if err := f.Await(context.Background()).Error(); err != ErrPoolClosed {
+			t.Logf("expected, %v, but got %v", ErrPoolClosed, err)
+			t.Fail()
+		}
+	}
+
+	close(release)
+	if v, err := running.Await(context.Background()).Get(); err != nil || v != 7 {
+		t.Logf("expected, %v, but got %v, %v", 7, v, err)
+		t.Fail()
+	}
+}
+
+func TestPoolShutdownBlockedSubmit(t *testing.T) {
+	p := NewPool[int](1, 1)
+	release := make(chan struct{})
+	defer close(release)
+	task, started := blockingTask(0, release)
+	p.Submit(task)
+	<-started
+	p.Submit(func() (int, error) { return 1, nil }) // Fills the queue
+
+	blocked := make(chan Future[int])
+	go func() {
+		blocked <- p.Submit(func() (int, error) { return 2, nil })
+	}()
+	for p.Metrics().Queued != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- p.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdown:
+		if err != context.DeadlineExceeded {
+			t.Logf("expected, %v, but got %v", context.DeadlineExceeded, err)
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Shutdown to return once ctx is done")
+	}
+	if err := (<-blocked).Await(context.Background()).Error(); err != ErrPoolClosed {
+		t.Logf("expected, %v, but got %v", ErrPoolClosed, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,144 @@
+package chanz
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Panic is a value recovered from a panic in a stage, along with the stack trace of where it happened
+type Panic struct {
+	Value any
+	Stack []byte
+}
+
+func (p Panic) Error() string {
+	return fmt.Sprintf("chanz: recovered from panic: %v", p.Value)
+}
+
+// OpRecover makes a stage recover from panics in its goroutines, such as a panic in the func passed to Map, and pass
+// them to handler. If handler returns true the item that caused the panic is skipped and the stage carries on,
+// otherwise the stage shuts down, closes its output chans and drains its input chans, as if OpDrain was supplied, so
+// that stages upstream are not left blocked. Without OpRecover, the default set by SetDefaultRecover
+// is used, and if there is none, a panic will crash the program as usual.
+func OpRecover(handler func(p Panic) (skip bool)) Option {
+	return func(s settings) settings {
+		s.recover = handler
+		return s
+	}
+}
+
+// OpRecoverTo makes a stage recover from panics in its goroutines, write them to errs and then shut down and close its
+// output chans. Writing to errs blocks, so it should be buffered or read by someone.
+func OpRecoverTo(errs chan<- error) Option {
+	return OpRecover(func(p Panic) bool {
+		errs <- p
+		return false
+	})
+}
+
+var defaultRecover atomic.Value
+
+// SetDefaultRecover sets the handler used by stages that does not have one supplied by OpRecover. A nil handler
+// removes the default, which makes panics crash the program as usual.
+func SetDefaultRecover(handler func(p Panic) (skip bool)) {
+	defaultRecover.Store(handler)
+}
+
+func recoverHandler(s settings) func(p Panic) bool {
+	if s.recover != nil {
+		return s.recover
+	}
+	handler, _ := defaultRecover.Load().(func(p Panic) bool)
+	return handler
+}
+
+// stopper is closed once a stage is stopped early, by a recover handler or at the first error with OpFailFast. The
+// stages that a composite stage, such as KeyedMap, is made of share one, so that they all stop together.
+type stopper struct {
+	quit chan struct{}
+	once sync.Once
+}
+
+func (st *stopper) stop() {
+	if st != nil {
+		st.once.Do(func() { close(st.quit) })
+	}
+}
+
+// stoppable gives s a stopper, unless it already has one, or there is no recover handler and OpFailFast is not
+// supplied, in which case the stage can not be stopped early. It is called by every stage that runs user supplied funcs
+// using call.
+func stoppable(s settings) settings {
+	if s.stop == nil && (recoverHandler(s) != nil || s.failFast) {
+		s.stop = &stopper{quit: make(chan struct{})}
+	}
+	return s
+}
+
+// opStopper makes a stage share st, rather than have a stopper of its own
+func opStopper(st *stopper) Option {
+	return func(s settings) settings {
+		s.stop = st
+		return s
+	}
+}
+
+// stopped returns a chan that is closed once the stage is stopped early, or nil if it has no stopper
+func stopped(s settings) <-chan struct{} {
+	if s.stop == nil {
+		return nil
+	}
+	return s.stop.quit
+}
+
+const (
+	callOk = iota
+	callSkip
+	callStop
+)
+
+// call runs f, a user supplied func applied to one item, and recovers from a panic in it if there is a recover
+// handler. It returns callOk if f did not panic, and otherwise callSkip or callStop depending on the handler, in which
+// case the stopper of the stage is closed as well. The time spent in f is reported to the Observer, if there is one.
+func call(s settings, f func()) (res int) {
+	if s.observer != nil {
+		start := time.Now()
+		defer func() {
+			s.observer.Processed(s.name, time.Since(start))
+		}()
+	}
+
+	handler := recoverHandler(s)
+	if handler == nil {
+		f()
+		return callOk
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			res = callSkip
+			if !handler(Panic{Value: r, Stack: debug.Stack()}) {
+				res = callStop
+				s.stop.stop()
+			}
+		}
+	}()
+	f()
+	return callOk
+}
+
+// recoverStage is deferred first thing in stage goroutines. If there is a recover handler it recovers from panics
+// that was not caught by call and passes them to the handler, after which the goroutine is let to end, running the
+// rest of its deferred funcs, such as closing its output chans. The stopper of the stage is closed as well.
+func recoverStage(s settings) {
+	handler := recoverHandler(s)
+	if handler == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		s.stop.stop()
+		handler(Panic{Value: r, Stack: debug.Stack()})
+	}
+}
@@ -0,0 +1,176 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/slicez"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func panicOn(n int) func(a int) int {
+	return func(a int) int {
+		if a == n {
+			panic("bad number")
+		}
+		return a
+	}
+}
+
+func TestRecoverSkip(t *testing.T) {
+	var mu sync.Mutex
+	var panics []Panic
+	mapped := Map(Generate(1, 2, 3, 4), panicOn(3), OpRecover(func(p Panic) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		panics = append(panics, p)
+		return true
+	}))
+
+	res := Collect(mapped)
+	exp := []int{1, 2, 4}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	if len(panics) != 1 || panics[0].Value != "bad number" || !strings.Contains(string(panics[0].Stack), "panicOn") {
+		t.Logf("expected one panic with value and stack, but got %v", panics)
+		t.Fail()
+	}
+}
+
+func TestRecoverStop(t *testing.T) {
+	errs := make(chan error, 1)
+	mapped := Map(Generate(1, 2, 3, 4), panicOn(3), OpRecoverTo(errs))
+
+	res := Collect(mapped)
+	exp := []int{1, 2}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	if err := <-errs; !strings.Contains(err.Error(), "bad number") {
+		t.Logf("expected panic error, but got %v", err)
+		t.Fail()
+	}
+}
+
+func TestRecoverPartition(t *testing.T) {
+	errs := make(chan error, 1)
+	sat, not := Partition(Generate(1, 2, 3, 4), func(a int) bool {
+		return panicOn(3)(a)%2 == 0
+	}, OpRecoverTo(errs))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		DropAll(sat, false)
+	}()
+	DropAll(not, false)
+	wg.Wait()
+
+	if len(errs) != 1 {
+		t.Log("expected the panic to be reported")
+		t.Fail()
+	}
+}
+
+func TestRecoverWorkers(t *testing.T) {
+	for _, unordered := range []bool{false, true} {
+		options := []Option{OpWorkers(3), OpRecover(func(p Panic) bool { return false })}
+		if unordered {
+			options = append(options, OpUnordered())
+		}
+
+		in := make([]int, 100)
+		for i := range in {
+			in[i] = i
+		}
+		res := Collect(Map(Generate(in...), panicOn(50), options...))
+		if len(res) >= 100 {
+			t.Logf("expected the stage to stop, but got %d items", len(res))
+			t.Fail()
+		}
+	}
+}
+
+func TestSetDefaultRecover(t *testing.T) {
+	var recovered int
+	SetDefaultRecover(func(p Panic) bool {
+		recovered++
+		return true
+	})
+	defer SetDefaultRecover(nil)
+
+	f := Filter(Generate(1, 2, 3, 4), func(a int) bool {
+		return panicOn(2)(a) > 0
+	})
+
+	res := Collect(f)
+	exp := []int{1, 3, 4}
+	if !slicez.Equal(res, exp) || recovered != 1 {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+// unbuffered writes 1 to n onto the return chan, which is unbuffered, and closes finished once every item is written
+func unbuffered(n int) (<-chan int, <-chan struct{}) {
+	c := make(chan int)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer close(c)
+		for i := 1; i <= n; i++ {
+			c <- i
+		}
+	}()
+	return c, finished
+}
+
+func TestRecoverStopDrainsInput(t *testing.T) {
+	stop := OpRecover(func(p Panic) bool { return false })
+	stages := map[string]func(in <-chan int) <-chan int{
+		"Map":        func(in <-chan int) <-chan int { return Map(in, panicOn(3), stop) },
+		"MapWorkers": func(in <-chan int) <-chan int { return Map(in, panicOn(3), stop, OpWorkers(2)) },
+		"MapUnordered": func(in <-chan int) <-chan int {
+			return Map(in, panicOn(3), stop, OpWorkers(2), OpUnordered())
+		},
+		"KeyedMap": func(in <-chan int) <-chan int {
+			return KeyedMap(in, 3, func(a int) int { return a % 3 }, panicOn(3), stop)
+		},
+		"MergeSorted": func(in <-chan int) <-chan int { // A panic in less stops the stage, even if it is skipped
+			less := func(a, b int) bool {
+				panicOn(3)(a)
+				return a < b
+			}
+			return MergeSortedWith[int](OpRecover(func(p Panic) bool { return true }))(less, in, Generate(0, 200))
+		},
+	}
+
+	for name, stage := range stages {
+		t.Run(name, func(t *testing.T) {
+			in, finished := unbuffered(100)
+			out := stage(in)
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				for range out {
+				}
+			}()
+			select {
+			case <-closed:
+			case <-time.After(time.Second):
+				t.Fatal("expected the output to be closed")
+			}
+			select {
+			case <-finished:
+			case <-time.After(time.Second):
+				t.Fatal("expected the producer not to be left blocked")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package chanz
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+)
+
+// hashKey returns a hash of k that is stable between runs and processes. Keys that are not strings, byte slices or
+// integers are hashed by their %#v representation, which is only stable if it does not include a pointer address, so
+// such keys must not be, or contain, pointers, since items of equal keys would otherwise end up on different chans
+// between runs.
+func hashKey[K comparable](k K) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	switch v := any(k).(type) {
+	case string:
+		_, _ = h.Write([]byte(v))
+	case []byte:
+		_, _ = h.Write(v)
+	case int:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		_, _ = h.Write(buf[:])
+	case int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		_, _ = h.Write(buf[:])
+	case uint64:
+		binary.LittleEndian.PutUint64(buf[:], v)
+		_, _ = h.Write(buf[:])
+	default:
+		_, _ = fmt.Fprintf(h, "%#v", v)
+	}
+	return h.Sum64()
+}
+
+// ShardBy returns a slice of n chans and writes every item read from the input chan to one of them, picked by a
+// stable hash of the items key. All items with the same key are written, in order, to the same chan. If n is 0, every
+// item is read and discarded, and a negative n panics.
+// The return chans has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func ShardBy[A any, K comparable](in <-chan A, n int, key func(a A) K, options ...Option) []<-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+	if n < 0 {
+		panic(fmt.Sprintf("chanz: ShardBy is given %d chans, but n can not be negative", n))
+	}
+
+	outs := make([]chan A, n)
+	for i := range outs {
+		outs[i] = make(chan A, s.buffer)
+	}
+
+	go func() {
+		defer recoverStage(s)
+		defer drain(s, in)
+		defer func() {
+			for _, o := range outs {
+				close(o)
+			}
+			observeClosed(s)
+		}()
+		for {
+			e, more := receive(s, in)
+			if !more {
+				return
+			}
+			if n == 0 {
+				continue
+			}
+			var k K
+			switch call(s, func() { k = key(e) }) {
+			case callSkip:
+				continue
+			case callStop:
+				return
+			}
+			if !send(s, outs[hashKey(k)%uint64(n)], e) {
+				return
+			}
+		}
+	}()
+	return Readers(outs...)
+}
+
+// ShardByWith returns a slice of n chans and writes every item read from the input chan to one of them, picked by a
+// stable hash of the items key. All items with the same key are written, in order, to the same chan. If n is 0, every
+// item is read and discarded, and a negative n panics.
+// The return chans has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func ShardByWith[A any, K comparable](options ...Option) func(in <-chan A, n int, key func(a A) K) []<-chan A {
+	return func(in <-chan A, n int, key func(a A) K) []<-chan A {
+		return ShardBy(in, n, key, options...)
+	}
+}
+
+// KeyedMap will take a chan, in, and executes mapper using up to "workers" goroutines. Items with the same key are
+// always mapped by the same goroutine, one at a time, so that their results are written in the same order as they were
+// read, while items with different keys are mapped in parallel, and may be reordered. If workers is less than 1, one
+// goroutine is used.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func KeyedMap[A any, B any, K comparable](in <-chan A, workers int, key func(a A) K, mapper func(a A) B, options ...Option) <-chan B {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	s = stoppable(s)
+	if workers < 1 {
+		workers = 1
+	}
+
+	// The stages that KeyedMap is made of are reported to the Observer as "name/shard", "name/map" and "name/merge", and
+	// share a stopper, so that all of them stop if a recover handler stops one of them
+	named := func(part string, extra ...Option) []Option {
+		return append(append(append([]Option{}, options...), OpName(s.name+"/"+part), opStopper(s.stop)), extra...)
+	}
+
+	shards := ShardBy(in, workers, key, named("shard")...)
+	mapped := make([]<-chan B, len(shards))
+	for i, shard := range shards {
+		mapped[i] = Map(shard, mapper, named("map", OpWorkers(1))...)
+	}
+	return FanInWith[B](named("merge")...)(mapped...)
+}
+
+// KeyedMapWith will take a chan, in, and executes mapper using up to "workers" goroutines. Items with the same key are
+// always mapped by the same goroutine, one at a time, so that their results are written in the same order as they were
+// read, while items with different keys are mapped in parallel, and may be reordered. If workers is less than 1, one
+// goroutine is used.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func KeyedMapWith[A any, B any, K comparable](options ...Option) func(in <-chan A, workers int, key func(a A) K, mapper func(a A) B) <-chan B {
+	return func(in <-chan A, workers int, key func(a A) K, mapper func(a A) B) <-chan B {
+		return KeyedMap(in, workers, key, mapper, options...)
+	}
+}
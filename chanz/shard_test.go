@@ -0,0 +1,110 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/slicez"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type keyedEvent struct {
+	Key string
+	Seq int
+}
+
+func keyedEvents() []keyedEvent {
+	var events []keyedEvent
+	for i := 0; i < 60; i++ {
+		events = append(events, keyedEvent{Key: []string{"a", "b", "c", "d"}[i%4], Seq: i})
+	}
+	return events
+}
+
+func TestShardBy(t *testing.T) {
+	shards := ShardBy(Generate(keyedEvents()...), 3, func(e keyedEvent) string {
+		return e.Key
+	})
+
+	res := collectAll(shards)
+	seen := map[string]int{}
+	for i, shard := range res {
+		for _, e := range shard {
+			if j, ok := seen[e.Key]; ok && j != i {
+				t.Logf("expected key %s to only be written to shard %d, but it was written to %d as well", e.Key, j, i)
+				t.Fail()
+			}
+			seen[e.Key] = i
+		}
+	}
+
+	if hashKey("a") != hashKey("a") || hashKey(1) != hashKey(1) {
+		t.Log("expected hashKey to be stable")
+		t.Fail()
+	}
+}
+
+func TestShardByNone(t *testing.T) {
+	in, finished := unbuffered(10)
+	if shards := ShardBy(in, 0, func(a int) int { return a }); len(shards) != 0 {
+		t.Logf("expected no shards, but got %d", len(shards))
+		t.Fail()
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Log("expected in to be read until it is closed")
+		t.Fail()
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Log("expected a panic for a negative number of shards")
+			t.Fail()
+		}
+	}()
+	ShardBy(Generate(1), -1, func(a int) int { return a })
+}
+
+func TestKeyedMap(t *testing.T) {
+	var mu sync.Mutex
+	running := map[string]bool{}
+
+	mapped := KeyedMap(Generate(keyedEvents()...), 3, func(e keyedEvent) string {
+		return e.Key
+	}, func(e keyedEvent) keyedEvent {
+		mu.Lock()
+		if running[e.Key] {
+			t.Logf("expected key %s to be mapped one at a time", e.Key)
+			t.Fail()
+		}
+		running[e.Key] = true
+		mu.Unlock()
+
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+
+		mu.Lock()
+		running[e.Key] = false
+		mu.Unlock()
+		return e
+	})
+
+	res := Collect(mapped)
+	if len(res) != 60 {
+		t.Logf("expected 60 items, but got %d", len(res))
+		t.Fail()
+	}
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		seqs := slicez.Map(slicez.Filter(res, func(e keyedEvent) bool {
+			return e.Key == key
+		}), func(e keyedEvent) int {
+			return e.Seq
+		})
+		if !sort.IntsAreSorted(seqs) {
+			t.Logf("expected items with key %s to keep their order, but got %v", key, seqs)
+			t.Fail()
+		}
+	}
+}
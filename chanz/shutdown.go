@@ -0,0 +1,89 @@
+package chanz
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrDone is returned by terminals, such as Fold, that stops because "done" is closed before their input chan
+var ErrDone = errors.New("chanz: done before input was closed")
+
+// OpDrain makes a stage that stops because "done" is closed keep reading, and discarding, items from its input chans
+// until they are closed. This guarantees that stages and producers upstream are never left blocked writing to a stage
+// that has stopped, even if they do not share the same "done" chan.
+func OpDrain() Option {
+	return func(s settings) settings {
+		s.drain = true
+		return s
+	}
+}
+
+// receive reads an item from c. It returns false if c is closed, if "done" is closed, which is supplied in Option, or if
+// the stage is stopped early, by a recover handler or OpFailFast
+func receive[A any](s settings, c <-chan A) (A, bool) {
+	select {
+	case <-s.done:
+		var zero A
+		return zero, false
+	case <-stopped(s):
+		var zero A
+		return zero, false
+	case e, ok := <-c:
+		if ok {
+			observeReceived(s)
+		}
+		return e, ok
+	}
+}
+
+// receiveErr reads an item from c, like receive, but returns the error from doneErr if it stops because "done" is
+// closed
+func receiveErr[A any](s settings, c <-chan A) (A, bool, error) {
+	select {
+	case <-s.done:
+		var zero A
+		return zero, false, doneErr(s)
+	case e, ok := <-c:
+		if ok {
+			observeReceived(s)
+		}
+		return e, ok, nil
+	}
+}
+
+// doneErr returns the error of the context supplied by OpContext, if it is cancelled, and ErrDone otherwise
+func doneErr(s settings) error {
+	if s.ctx != nil && s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+	return ErrDone
+}
+
+// drain is deferred by stages, to run once their output chans are closed. If OpDrain is supplied and the stage has
+// stopped because "done" is closed, or if the stage has been stopped early, by a recover handler or OpFailFast, it reads
+// and discards items from cs until they are all closed.
+func drain[A any](s settings, cs ...<-chan A) {
+	select {
+	case <-stopped(s):
+	default:
+		if !s.drain {
+			return
+		}
+		select {
+		case <-s.done:
+		default:
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(cs))
+	for _, c := range cs {
+		go func(c <-chan A) {
+			defer wg.Done()
+			for range c {
+			}
+		}(c)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,209 @@
+package chanz
+
+import (
+	"context"
+	"github.com/modfin/henry/mon"
+	"github.com/modfin/henry/slicez"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// producer writes n items to the return chan and then closes it, without watching any done chan
+func producer(n int) <-chan int {
+	c := make(chan int)
+	go func() {
+		defer close(c)
+		for i := 0; i < n; i++ {
+			c <- i
+		}
+	}()
+	return c
+}
+
+// generator writes n items to the return chan and then closes it, or stops once done is closed
+func generator(done <-chan struct{}, n int) <-chan int {
+	return GenerateWith[int](OpDone(done))(slicez.RepeatBy(n, func(i int) int { return i })...)
+}
+
+// waitForGoroutines waits until there are no more than n goroutines running
+func waitForGoroutines(n int) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= n {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestShutdownLeavesNoGoroutines(t *testing.T) {
+	identity := func(a int) int { return a }
+	even := func(a int) bool { return a%2 == 0 }
+
+	stages := map[string]func(in func() <-chan int, options ...Option) <-chan int{
+		"Map": func(in func() <-chan int, o ...Option) <-chan int { return Map(in(), identity, o...) },
+		"MapWorkers": func(in func() <-chan int, o ...Option) <-chan int {
+			return Map(in(), identity, append(o, OpWorkers(3))...)
+		},
+		"MapUnordered": func(in func() <-chan int, o ...Option) <-chan int {
+			return Map(in(), identity, append(o, OpWorkers(3), OpUnordered())...)
+		},
+		"Filter": func(in func() <-chan int, o ...Option) <-chan int { return Filter(in(), even, o...) },
+		"Flatten": func(in func() <-chan int, o ...Option) <-chan int {
+			return Flatten(Map(in(), func(a int) []int { return []int{a, a} }, o...), o...)
+		},
+		"FanIn":  func(in func() <-chan int, o ...Option) <-chan int { return FanInWith[int](o...)(in(), in()) },
+		"FanOut": func(in func() <-chan int, o ...Option) <-chan int { return FanOut(in(), 2, o...)[0] },
+		"Concat": func(in func() <-chan int, o ...Option) <-chan int { return ConcatWith[int](o...)(in(), in()) },
+		"Compact": func(in func() <-chan int, o ...Option) <-chan int {
+			return Compact(in(), func(a, b int) bool { return a == b }, o...)
+		},
+		"Partition": func(in func() <-chan int, o ...Option) <-chan int { sat, _ := Partition(in(), even, o...); return sat },
+		"TakeWhile": func(in func() <-chan int, o ...Option) <-chan int {
+			return TakeWhile(in(), func(a int) bool { return a >= 0 }, o...)
+		},
+		"Take":      func(in func() <-chan int, o ...Option) <-chan int { return Take(in(), 50, o...) },
+		"Drop":      func(in func() <-chan int, o ...Option) <-chan int { return Drop(in(), 1, o...) },
+		"DropWhile": func(in func() <-chan int, o ...Option) <-chan int { return DropWhile(in(), even, o...) },
+		"Zip": func(in func() <-chan int, o ...Option) <-chan int {
+			return Zip(in(), in(), func(a, b int) int { return a + b }, o...)
+		},
+		"Unzip": func(in func() <-chan int, o ...Option) <-chan int {
+			a, _ := Unzip(in(), func(c int) (int, int) { return c, c }, o...)
+			return a
+		},
+		"MapErr": func(in func() <-chan int, o ...Option) <-chan int {
+			return Map(MapErr(in(), func(a int) (int, error) { return a, nil }, o...), func(r mon.Result[int]) int {
+				return r.OrEmpty()
+			}, o...)
+		},
+		"Batch": func(in func() <-chan int, o ...Option) <-chan int {
+			return WindowFold(Batch(in(), 10, time.Hour, o...), sumOf, o...)
+		},
+		"Throttle": func(in func() <-chan int, o ...Option) <-chan int {
+			return Throttle(in(), 1, time.Hour, ThrottleBlock, o...)
+		},
+		"Debounce":    func(in func() <-chan int, o ...Option) <-chan int { return Debounce(in(), time.Hour, o...) },
+		"SampleEvery": func(in func() <-chan int, o ...Option) <-chan int { return SampleEvery(in(), time.Hour, o...) },
+		"WindowCount": func(in func() <-chan int, o ...Option) <-chan int {
+			return WindowFold(WindowCount(in(), 3, 1, o...), sumOf, o...)
+		},
+		"WindowTime": func(in func() <-chan int, o ...Option) <-chan int {
+			return WindowFold(WindowTime(in(), time.Hour, time.Hour, o...), sumOf, o...)
+		},
+		"Distribute": func(in func() <-chan int, o ...Option) <-chan int {
+			return Distribute(in(), 2, DistributeRoundRobin, o...)[0]
+		},
+		"DistributeFirstFree": func(in func() <-chan int, o ...Option) <-chan int {
+			return Distribute(in(), 2, DistributeFirstFree, o...)[0]
+		},
+		"MergeSorted": func(in func() <-chan int, o ...Option) <-chan int {
+			return MergeSortedWith[int](o...)(func(a, b int) bool { return a < b }, in(), in())
+		},
+		"PriorityMerge": func(in func() <-chan int, o ...Option) <-chan int { return PriorityMergeWith[int](o...)(in(), in()) },
+		"ShardBy":       func(in func() <-chan int, o ...Option) <-chan int { return ShardBy(in(), 2, identity, o...)[0] },
+		"Broadcast":     func(in func() <-chan int, o ...Option) <-chan int { return Broadcast(in(), o...).Subscribe(o...) },
+		"KeyedMap":      func(in func() <-chan int, o ...Option) <-chan int { return KeyedMap(in(), 2, identity, identity, o...) },
+		"Elastic": func(in func() <-chan int, o ...Option) <-chan int {
+			out, _ := Elastic(in(), o...)
+			return out
+		},
+		"Scan": func(in func() <-chan int, o ...Option) <-chan int {
+			return Scan(in(), func(acc int, a int) int { return acc + a }, 0, o...)
+		},
+		"GroupBy": func(in func() <-chan int, o ...Option) <-chan int {
+			groups := GroupBy(in(), func(a int) int { return a % 3 }, o...)
+			return Map(groups, func(g Group[int, int]) int { return g.Key }, o...)
+		},
+		"Distinct": func(in func() <-chan int, o ...Option) <-chan int { return Distinct(in(), identity, o...) },
+		"DistinctWindow": func(in func() <-chan int, o ...Option) <-chan int {
+			return DistinctWindow(in(), identity, 10, time.Hour, o...)
+		},
+		"JoinWindow": func(in func() <-chan int, o ...Option) <-chan int {
+			return JoinWindow(in(), in(), identity, identity, time.Hour, func(l, r mon.Option[int]) int {
+				return l.OrElse(0) + r.OrElse(0)
+			}, o...)
+		},
+		"CombineLatest": func(in func() <-chan int, o ...Option) <-chan int {
+			return CombineLatest(in(), in(), func(a, b int) int { return a + b }, o...)
+		},
+		"ZipLongest": func(in func() <-chan int, o ...Option) <-chan int {
+			return ZipLongest(in(), in(), func(a, b int) int { return a + b }, 0, 0, o...)
+		},
+		"FlatMap": func(in func() <-chan int, o ...Option) <-chan int {
+			return FlatMap(in(), func(a int) []int { return []int{a, a} }, o...)
+		},
+		"MergeMap": func(in func() <-chan int, o ...Option) <-chan int {
+			return MergeMap(in(), func(ctx context.Context, a int) <-chan int { return repeat(ctx, a, 2) }, 2, o...)
+		},
+		"SwitchMap": func(in func() <-chan int, o ...Option) <-chan int {
+			return SwitchMap(in(), func(ctx context.Context, a int) <-chan int { return repeat(ctx, a, 2) }, o...)
+		},
+		"FromReader": func(_ func() <-chan int, o ...Option) <-chan int {
+			lines, _ := FromReader(strings.NewReader(strings.Repeat("line\n", 100)), nil, o...)
+			return Map(lines, func(string) int { return 1 }, o...)
+		},
+		"Interval": func(_ func() <-chan int, o ...Option) <-chan int { return Interval(time.Millisecond, o...) },
+		"BroadcastClose": func(in func() <-chan int, o ...Option) <-chan int {
+			b := Broadcast(in(), o...)
+			sub := b.Subscribe(o...)
+			b.Close()
+			return sub
+		},
+	}
+
+	// Either the producers ignore "done" and are drained through OpDrain, or they stop on "done" as well
+	modes := map[string]func(done <-chan struct{}) (func() <-chan int, []Option){
+		"Drain": func(done <-chan struct{}) (func() <-chan int, []Option) {
+			return func() <-chan int { return producer(100) }, []Option{OpDone(done), OpDrain()}
+		},
+		"Done": func(done <-chan struct{}) (func() <-chan int, []Option) {
+			return func() <-chan int { return generator(done, 100) }, []Option{OpDone(done)}
+		},
+	}
+
+	for mode, setup := range modes {
+		for name, stage := range stages {
+			t.Run(mode+"/"+name, func(t *testing.T) {
+				baseline := runtime.NumGoroutine()
+
+				done := make(chan struct{})
+				in, options := setup(done)
+				out := stage(in, options...)
+				select {
+				case <-out:
+				case <-time.After(10 * time.Millisecond):
+				}
+				close(done)
+
+				if !waitForGoroutines(baseline) {
+					t.Logf("expected %d goroutines, but got %d", baseline, runtime.NumGoroutine())
+					t.Fail()
+				}
+			})
+		}
+	}
+}
+
+func sumOf(w []int) int {
+	var sum int
+	for _, e := range w {
+		sum += e
+	}
+	return sum
+}
+
+func TestCollectDrain(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	done := make(chan struct{})
+	close(done)
+
+	Collect(producer(100), OpDone(done), OpDrain())
+	if !waitForGoroutines(baseline) {
+		t.Logf("expected %d goroutines, but got %d", baseline, runtime.NumGoroutine())
+		t.Fail()
+	}
+}
@@ -0,0 +1,322 @@
+package chanz
+
+import "time"
+
+// Batch takes a chan and groups the items read from it into slices of at most size items. A batch is written to the
+// output chan once it holds size items, or once maxWait has passed since the first item of the batch was read,
+// whichever happens first. Once "in" is closed, any remaining items are written as a last, partial, batch.
+// If size is less than 1, batches are only limited by maxWait. If maxWait is 0 or less, batches are only limited by size.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Batch[A any](in <-chan A, size int, maxWait time.Duration, options ...Option) <-chan []A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	clock := clockOf(s)
+	out := make(chan []A, s.buffer)
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+
+		var batch []A
+		var timer ClockTimer
+		var timeout <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(s, out, b)
+		}
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case e, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				observeReceived(s)
+				batch = append(batch, e)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = clock.NewTimer(maxWait)
+					timeout = timer.C()
+				}
+				if len(batch) == size && !flush() {
+					return
+				}
+			case <-timeout:
+				if !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// BatchWith takes a chan and groups the items read from it into slices of at most size items. A batch is written to the
+// output chan once it holds size items, or once maxWait has passed since the first item of the batch was read,
+// whichever happens first. Once "in" is closed, any remaining items are written as a last, partial, batch.
+// If size is less than 1, batches are only limited by maxWait. If maxWait is 0 or less, batches are only limited by size.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func BatchWith[A any](options ...Option) func(in <-chan A, size int, maxWait time.Duration) <-chan []A {
+	return func(in <-chan A, size int, maxWait time.Duration) <-chan []A {
+		return Batch(in, size, maxWait, options...)
+	}
+}
+
+// passThrough writes every item read from in onto out, until "in" or "done" is closed, for stages whose limit is off
+func passThrough[A any](s settings, in <-chan A, out chan<- A) {
+	for {
+		e, more := receive(s, in)
+		if !more {
+			return
+		}
+		if !send(s, out, e) {
+			return
+		}
+	}
+}
+
+// ThrottleMode decides what Throttle does with items once the limit for the interval has been reached
+type ThrottleMode int
+
+const (
+	ThrottleBlock ThrottleMode = iota
+	ThrottleDrop
+)
+
+// Throttle takes a chan and writes at most n items per interval, per, onto the return chan. If mode is ThrottleBlock,
+// reading from "in" is paused until the next interval once n items has been written. If mode is ThrottleDrop, items
+// read when n items already has been written during the interval are dropped. If per is 0 or less, or n is less than
+// 1, there is no limit and every item is written.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Throttle[A any](in <-chan A, n int, per time.Duration, mode ThrottleMode, options ...Option) <-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	clock := clockOf(s)
+	out := make(chan A, s.buffer)
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		if per <= 0 || n < 1 {
+			passThrough(s, in, out)
+			return
+		}
+
+		ticker := clock.NewTicker(per)
+		defer ticker.Stop()
+
+		tokens := n
+		for {
+			select { // A new interval takes precedence over new items
+			case <-ticker.C():
+				tokens = n
+			default:
+			}
+
+			if tokens == 0 && mode == ThrottleBlock {
+				select {
+				case <-s.done:
+					return
+				case <-ticker.C():
+					tokens = n
+				}
+				continue
+			}
+
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C():
+				tokens = n
+			case e, ok := <-in:
+				if !ok {
+					return
+				}
+				observeReceived(s)
+				if tokens == 0 {
+					continue
+				}
+				tokens--
+				if !send(s, out, e) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ThrottleWith takes a chan and writes at most n items per interval, per, onto the return chan. If mode is ThrottleBlock,
+// reading from "in" is paused until the next interval once n items has been written. If mode is ThrottleDrop, items
+// read when n items already has been written during the interval are dropped. If per is 0 or less, or n is less than
+// 1, there is no limit and every item is written.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func ThrottleWith[A any](options ...Option) func(in <-chan A, n int, per time.Duration, mode ThrottleMode) <-chan A {
+	return func(in <-chan A, n int, per time.Duration, mode ThrottleMode) <-chan A {
+		return Throttle(in, n, per, mode, options...)
+	}
+}
+
+// Debounce takes a chan and writes an item onto the return chan once no other item has been read for the duration
+// of quiet. Items that are followed by another item within the quiet period are dropped. Once "in" is closed, the
+// last item, if not yet written, is written straight away.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func Debounce[A any](in <-chan A, quiet time.Duration, options ...Option) <-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	clock := clockOf(s)
+	out := make(chan A, s.buffer)
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+
+		var last A
+		var pending bool
+		var timer ClockTimer
+		var timeout <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case e, ok := <-in:
+				if !ok {
+					if pending {
+						send(s, out, last)
+					}
+					return
+				}
+				observeReceived(s)
+				last, pending = e, true
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = clock.NewTimer(quiet)
+				timeout = timer.C()
+			case <-timeout:
+				pending, timeout = false, nil
+				if !send(s, out, last) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// DebounceWith takes a chan and writes an item onto the return chan once no other item has been read for the duration
+// of quiet. Items that are followed by another item within the quiet period are dropped. Once "in" is closed, the
+// last item, if not yet written, is written straight away.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func DebounceWith[A any](options ...Option) func(in <-chan A, quiet time.Duration) <-chan A {
+	return func(in <-chan A, quiet time.Duration) <-chan A {
+		return Debounce(in, quiet, options...)
+	}
+}
+
+// SampleEvery takes a chan and, on every tick of the interval d, writes the latest item read onto the return chan.
+// Nothing is written on a tick if no new item has been read since the previous tick, and items read after the
+// last tick are dropped once "in" is closed. If d is 0 or less, every item is written as it is read.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func SampleEvery[A any](in <-chan A, d time.Duration, options ...Option) <-chan A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+
+	clock := clockOf(s)
+	out := make(chan A, s.buffer)
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		if d <= 0 {
+			passThrough(s, in, out)
+			return
+		}
+
+		ticker := clock.NewTicker(d)
+		defer ticker.Stop()
+
+		var latest A
+		var fresh bool
+		for {
+			select {
+			case <-s.done:
+				return
+			case e, ok := <-in:
+				if !ok {
+					return
+				}
+				observeReceived(s)
+				latest, fresh = e, true
+			case <-ticker.C():
+				if !fresh {
+					continue
+				}
+				fresh = false
+				if !send(s, out, latest) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// SampleEveryWith takes a chan and, on every tick of the interval d, writes the latest item read onto the return chan.
+// Nothing is written on a tick if no new item has been read since the previous tick, and items read after the
+// last tick are dropped once "in" is closed. If d is 0 or less, every item is written as it is read.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func SampleEveryWith[A any](options ...Option) func(in <-chan A, d time.Duration) <-chan A {
+	return func(in <-chan A, d time.Duration) <-chan A {
+		return SampleEvery(in, d, options...)
+	}
+}
@@ -0,0 +1,178 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	clock := newFakeClock()
+	in := make(chan int)
+	batches := Batch(in, 3, time.Second, OpClock(clock))
+
+	in <- 1
+	in <- 2
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	res := <-batches
+	exp := []int{1, 2}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	go func() {
+		for _, i := range []int{3, 4, 5, 6} {
+			in <- i
+		}
+		close(in)
+	}()
+
+	res = <-batches
+	exp = []int{3, 4, 5}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	res = <-batches
+	exp = []int{6}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+
+	if _, ok := <-batches; ok {
+		t.Log("expected batches to be closed")
+		t.Fail()
+	}
+}
+
+func TestBatchSizeOnly(t *testing.T) {
+	res := Collect(Batch(Generate(1, 2, 3, 4, 5), 2, 0))
+	exp := [][]int{{1, 2}, {3, 4}, {5}}
+	if !slicez.EqualBy(res, exp, slicez.Equal[int]) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestThrottleDrop(t *testing.T) {
+	clock := newFakeClock()
+	in := make(chan int)
+	throttled := Throttle(in, 2, time.Second, ThrottleDrop, OpClock(clock), OpBuffer(10))
+	clock.BlockUntil(1)
+
+	for i := 1; i <= 5; i++ {
+		in <- i
+	}
+	clock.Advance(time.Second)
+	in <- 6
+	close(in)
+
+	res := Collect(throttled)
+	exp := []int{1, 2, 6}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestThrottleBlock(t *testing.T) {
+	clock := newFakeClock()
+	throttled := Throttle(Generate(1, 2, 3), 2, time.Second, ThrottleBlock, OpClock(clock))
+	clock.BlockUntil(1)
+
+	res := []int{<-throttled, <-throttled}
+	select {
+	case v := <-throttled:
+		t.Logf("expected throttle to block, but got %v", v)
+		t.Fail()
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	res = append(res, Collect(throttled)...)
+	exp := []int{1, 2, 3}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	clock := newFakeClock()
+	in := make(chan int)
+	debounced := Debounce(in, time.Second, OpClock(clock))
+
+	in <- 1
+	clock.BlockUntilDue(clock.Now().Add(time.Second))
+	clock.Advance(500 * time.Millisecond)
+	in <- 2
+	clock.BlockUntilDue(clock.Now().Add(time.Second))
+	clock.Advance(time.Second)
+
+	if v := <-debounced; v != 2 {
+		t.Logf("expected, %v, but got %v", 2, v)
+		t.Fail()
+	}
+
+	in <- 3
+	close(in)
+	res := Collect(debounced)
+	exp := []int{3}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestSampleEvery(t *testing.T) {
+	clock := newFakeClock()
+	in := make(chan int)
+	sampled := SampleEvery(in, time.Second, OpClock(clock))
+	clock.BlockUntil(1)
+
+	in <- 1
+	in <- 2
+	clock.Advance(time.Second)
+	if v := <-sampled; v != 2 {
+		t.Logf("expected, %v, but got %v", 2, v)
+		t.Fail()
+	}
+
+	clock.Advance(time.Second)
+	in <- 3
+	clock.Advance(time.Second)
+	if v := <-sampled; v != 3 {
+		t.Logf("expected, %v, but got %v", 3, v)
+		t.Fail()
+	}
+
+	close(in)
+	if res := Collect(sampled); len(res) != 0 {
+		t.Logf("expected no more samples, but got %v", res)
+		t.Fail()
+	}
+}
+
+func TestNoInterval(t *testing.T) {
+	exp := []int{1, 2, 3}
+	res := Collect(Throttle(Generate(1, 2, 3), 1, 0, ThrottleDrop))
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	res = Collect(Throttle(Generate(1, 2, 3), 0, time.Hour, ThrottleBlock))
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+	res = Collect(SampleEvery(Generate(1, 2, 3), -time.Second))
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
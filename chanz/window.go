@@ -0,0 +1,230 @@
+package chanz
+
+import "time"
+
+// discard reads and discards items from in until it is closed, or the stage stops
+func discard[A any](s settings, in <-chan A) {
+	for {
+		if _, more := receive(s, in); !more {
+			return
+		}
+	}
+}
+
+// WindowCount takes a chan and writes windows of size items onto the return chan. A new window is started every
+// step items, so if step equals size the windows are tumbling, if step is less than size they are sliding and overlap,
+// and if step is greater than size the items in between windows are dropped. A window is written once it holds size
+// items. Once "in" is closed, every window that has been started but is not yet full is written as a partial window.
+// If step is less than 1, it is set to size. If size is less than 1, every item is read and discarded.
+// e.g. size 3 and step 1 on 1, 2, 3, 4, 5 gives [1 2 3], [2 3 4], [3 4 5], [4 5], [5]
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func WindowCount[A any](in <-chan A, size int, step int, options ...Option) <-chan []A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	if step < 1 {
+		step = size
+	}
+
+	out := make(chan []A, s.buffer)
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		if size < 1 {
+			discard(s, in)
+			return
+		}
+
+		var open [][]A
+		var i int
+		for {
+			e, more := receive(s, in)
+			if !more {
+				break
+			}
+			if i%step == 0 {
+				open = append(open, make([]A, 0, size))
+			}
+			i++
+			for j := range open {
+				open[j] = append(open[j], e)
+			}
+			if len(open) == 0 || len(open[0]) < size {
+				continue
+			}
+			if !send(s, out, open[0]) {
+				return
+			}
+			open = open[1:]
+		}
+
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		for _, w := range open {
+			if !send(s, out, w) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// WindowCountWith takes a chan and writes windows of size items onto the return chan. A new window is started every
+// step items, so if step equals size the windows are tumbling, if step is less than size they are sliding and overlap,
+// and if step is greater than size the items in between windows are dropped. A window is written once it holds size
+// items. Once "in" is closed, every window that has been started but is not yet full is written as a partial window.
+// If step is less than 1, it is set to size. If size is less than 1, every item is read and discarded.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func WindowCountWith[A any](options ...Option) func(in <-chan A, size int, step int) <-chan []A {
+	return func(in <-chan A, size int, step int) <-chan []A {
+		return WindowCount(in, size, step, options...)
+	}
+}
+
+// WindowTime takes a chan and writes windows of the items read during duration onto the return chan. The first window
+// starts when WindowTime is called and a new window is started every slide, so if slide equals duration the windows
+// are tumbling, and if slide is less than duration they are sliding and overlap. A window covers the half open
+// interval [start, start+duration) and is written at its end. Empty windows are not written. Once "in" is closed,
+// every window that has been started is written as a partial window.
+// If slide is 0 or less, it is set to duration. If duration is 0 or less, every item is read and discarded.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func WindowTime[A any](in <-chan A, duration time.Duration, slide time.Duration, options ...Option) <-chan []A {
+	var s settings
+	for _, o := range options {
+		s = o(s)
+	}
+	if slide <= 0 {
+		slide = duration
+	}
+
+	type window struct {
+		end   time.Time
+		items []A
+	}
+
+	clock := clockOf(s)
+	nextStart := clock.Now()
+	out := make(chan []A, s.buffer)
+	go func() {
+		defer drain(s, in)
+		defer observeClosed(s)
+		defer close(out)
+		if duration <= 0 {
+			discard(s, in)
+			return
+		}
+
+		var open []window
+		emit := func(w window) bool {
+			if len(w.items) == 0 {
+				return true
+			}
+			return send(s, out, w.items)
+		}
+
+		// advance closes every window that has ended and starts every window that has begun, as of now
+		advance := func(now time.Time) bool {
+			for len(open) > 0 && !open[0].end.After(now) {
+				if !emit(open[0]) {
+					return false
+				}
+				open = open[1:]
+			}
+			for !nextStart.After(now) {
+				open = append(open, window{end: nextStart.Add(duration)})
+				nextStart = nextStart.Add(slide)
+			}
+			return true
+		}
+
+		var timer ClockTimer
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		if !advance(nextStart) {
+			return
+		}
+		for {
+			if timer == nil {
+				next := nextStart
+				if len(open) > 0 && open[0].end.Before(next) {
+					next = open[0].end
+				}
+				timer = clock.NewTimer(next.Sub(clock.Now()))
+			}
+
+			select {
+			case <-s.done:
+				return
+			case now := <-timer.C():
+				timer = nil
+				if !advance(now) {
+					return
+				}
+			case e, ok := <-in:
+				if !ok {
+					for _, w := range open {
+						if !emit(w) {
+							return
+						}
+					}
+					return
+				}
+				observeReceived(s)
+				for i := range open {
+					open[i].items = append(open[i].items, e)
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// WindowTimeWith takes a chan and writes windows of the items read during duration onto the return chan. The first
+// window starts when WindowTime is called and a new window is started every slide, so if slide equals duration the
+// windows are tumbling, and if slide is less than duration they are sliding and overlap. A window covers the half open
+// interval [start, start+duration) and is written at its end. Empty windows are not written. Once "in" is closed,
+// every window that has been started is written as a partial window.
+// If slide is 0 or less, it is set to duration. If duration is 0 or less, every item is read and discarded.
+// The time is taken from the Clock supplied by OpClock, default is the system clock.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func WindowTimeWith[A any](options ...Option) func(in <-chan A, duration time.Duration, slide time.Duration) <-chan []A {
+	return func(in <-chan A, duration time.Duration, slide time.Duration) <-chan []A {
+		return WindowTime(in, duration, slide, options...)
+	}
+}
+
+// WindowFold takes a chan of windows, such as the one returned by WindowCount or WindowTime, and writes the result of
+// applying fold to each window onto the return chan. It allows aggregates to be calculated per window without
+// collecting the whole stream, e.g.
+//
+//	WindowFold(windows, func(w []float64) float64 { return numz.Mean(w...) })
+//
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func WindowFold[A any, B any](windows <-chan []A, fold func(window []A) B, options ...Option) <-chan B {
+	return Map(windows, fold, options...)
+}
+
+// WindowFoldWith takes a chan of windows, such as the one returned by WindowCount or WindowTime, and writes the result
+// of applying fold to each window onto the return chan.
+// The return chan has a buffer of buffer size supplied in input Option, default is 0.
+// It will stop once "in", "done" channel is closed or the context.Done is closed, which is supplied in Option
+func WindowFoldWith[A any, B any](options ...Option) func(windows <-chan []A, fold func(window []A) B) <-chan B {
+	return func(windows <-chan []A, fold func(window []A) B) <-chan B {
+		return WindowFold(windows, fold, options...)
+	}
+}
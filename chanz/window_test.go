@@ -0,0 +1,92 @@
+package chanz
+
+import (
+	"github.com/modfin/henry/numz"
+	"github.com/modfin/henry/slicez"
+	"testing"
+	"time"
+)
+
+func TestWindowCount(t *testing.T) {
+	tests := []struct {
+		size, step int
+		exp        [][]int
+	}{
+		{size: 2, step: 2, exp: [][]int{{1, 2}, {3, 4}, {5}}},
+		{size: 3, step: 1, exp: [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}, {4, 5}, {5}}},
+		{size: 1, step: 2, exp: [][]int{{1}, {3}, {5}}},
+	}
+
+	for _, test := range tests {
+		res := Collect(WindowCount(Generate(1, 2, 3, 4, 5), test.size, test.step))
+		if !slicez.EqualBy(res, test.exp, slicez.Equal[int]) {
+			t.Logf("expected, %v, but got %v, for size %d and step %d", test.exp, res, test.size, test.step)
+			t.Fail()
+		}
+	}
+}
+
+func TestWindowTime(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	in := make(chan int)
+	windows := WindowTime(in, 2*time.Second, time.Second, OpClock(clock), OpBuffer(10))
+
+	// t=0s, windows [0,2) is open
+	in <- 1
+	clock.BlockUntilDue(start.Add(time.Second))
+	clock.Advance(time.Second)
+
+	// t=1s, windows [0,2) and [1,3) are open
+	in <- 2
+	clock.BlockUntilDue(start.Add(2 * time.Second))
+	clock.Advance(time.Second)
+
+	// t=2s, [0,2) is written, windows [1,3) and [2,4) are open
+	in <- 3
+	close(in)
+
+	res := Collect(windows)
+	exp := [][]int{{1, 2}, {2, 3}, {3}}
+	if !slicez.EqualBy(res, exp, slicez.Equal[int]) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestWindowFold(t *testing.T) {
+	windows := WindowCount(Generate(1.0, 2.0, 3.0, 4.0, 5.0, 6.0), 3, 3)
+	means := WindowFold(windows, func(w []float64) float64 {
+		return numz.Mean(w...)
+	})
+
+	res := Collect(means)
+	exp := []float64{2, 5}
+	if !slicez.Equal(res, exp) {
+		t.Logf("expected, %v, but got %v", exp, res)
+		t.Fail()
+	}
+}
+
+func TestWindowEmpty(t *testing.T) {
+	stages := map[string]func(in <-chan int) <-chan []int{
+		"WindowCount": func(in <-chan int) <-chan []int { return WindowCount(in, 0, 1) },
+		"WindowTime":  func(in <-chan int) <-chan []int { return WindowTime(in, 0, time.Second) },
+	}
+
+	for name, stage := range stages {
+		t.Run(name, func(t *testing.T) {
+			in, finished := unbuffered(10)
+			if res := Collect(stage(in)); len(res) != 0 {
+				t.Logf("expected no windows, but got %v", res)
+				t.Fail()
+			}
+			select {
+			case <-finished:
+			case <-time.After(time.Second):
+				t.Log("expected in to be read until it is closed")
+				t.Fail()
+			}
+		})
+	}
+}